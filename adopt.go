@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// adoptableTypes are the types of the records external-dns can take ownership
+// of. Route53 alias records are A or AAAA records.
+var adoptableTypes = []string{"A", "AAAA", "CNAME"}
+
+// adoptRecords will look for the records of the hostnames found in the cluster
+// that have no external-DNS TXT records and create the TXT records that mark
+// them as owned by the owner ID.
+func adoptRecords(provider DNSProvider, hostnames []kubeHostname, registry txtRegistry, owner string, opts runOptions) error {
+	records, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+	changes, err := planAdoption(hostnames, records, registry, owner, quotedTXT(provider))
+	if err != nil {
+		return err
+	}
+	return executeChanges(provider, records, changes, opts)
+}
+
+// planAdoption returns the changes needed to create a typed TXT record, owned
+// by the owner ID, for every record of the hostnames that has no external-DNS
+// TXT record. Hostnames that have any TXT record are already owned and are
+// left untouched.
+func planAdoption(hostnames []kubeHostname, records []Record, registry txtRegistry, owner string, quoted bool) ([]change, error) {
+	var changes []change
+	resources := hostnameResources(hostnames)
+	planned := map[string]bool{}
+	for _, h := range hostnames {
+		hostname := sanitizeDNSAddress(h.Hostname)
+		if planned[hostname] {
+			continue
+		}
+		planned[hostname] = true
+
+		dataRecords := adoptableRecords(hostname, records)
+		if len(dataRecords) == 0 || registryTXTRecordsExist(hostname, dataRecords, registry, records) {
+			continue
+		}
+		l := labels{labelOwner: owner}
+		if resources[hostname] != "" {
+			l[labelResource] = resources[hostname]
+		}
+		value, err := registry.formatValue(l, txtFormat{quoted: quoted, encrypted: len(registry.aesKey) > 0})
+		if err != nil {
+			return nil, fmt.Errorf("Cannot create TXT record value for: %s, %v", hostname, err)
+		}
+		var planned []Record
+		for _, record := range dataRecords {
+			// Cloudflare lists a record for every value, and Route53 record
+			// sets with a routing policy have a TXT record of the same policy
+			txt := Record{
+				Name:          sanitizeDNSAddress(registry.typedTXTName(hostname, record.Type)),
+				Type:          "TXT",
+				TTL:           record.TTL,
+				Values:        []string{value},
+				RoutingPolicy: record.RoutingPolicy,
+			}
+			if findRecord(planned, txt) >= 0 {
+				continue
+			}
+			changes = append(changes, createChange(hostname, txt))
+			planned = append(planned, txt)
+		}
+	}
+	return changes, nil
+}
+
+// adoptableRecords returns the records of the hostname that external-dns can
+// take ownership of
+func adoptableRecords(hostname string, records []Record) []Record {
+	var adoptable []Record
+	for _, record := range records {
+		if record.Name == hostname && slices.Contains(adoptableTypes, record.Type) {
+			adoptable = append(adoptable, record)
+		}
+	}
+	return adoptable
+}
+
+// registryTXTRecordsExist returns true if any of the legacy or typed TXT
+// records of the hostname records exist
+func registryTXTRecordsExist(hostname string, dataRecords []Record, registry txtRegistry, records []Record) bool {
+	for _, record := range dataRecords {
+		for _, name := range registry.txtNames(hostname, record.Type) {
+			if _, ok := lookupTXTRecord(name, records); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
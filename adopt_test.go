@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanAdoption(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	records := []Record{
+		// Unowned records of two types
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("a.example.com", "AAAA", "::1"),
+		// Owned with a legacy TXT record
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("other", "")),
+		// Not a type external-dns manages
+		testRecord("c.example.com", "MX", "10 mx.example.com."),
+		testRecord("d.example.com", "CNAME", "lb.example.net."),
+		// Cloudflare lists a record for every value
+		withID(testRecord("f.example.com", "A", "6.6.6.6"), "1"),
+		withID(testRecord("f.example.com", "A", "6.6.6.7"), "2"),
+		// Route53 weighted record sets
+		withSetIdentifier(testRecord("g.example.com", "A", "7.7.7.7"), "blue"),
+		withSetIdentifier(testRecord("g.example.com", "A", "7.7.7.8"), "green"),
+	}
+	tests := []struct {
+		name      string
+		hostnames []kubeHostname
+		quoted    bool
+		want      []string
+	}{
+		{
+			name:      "creates a typed TXT record for every record type",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Resource: "ingress/ns/a"}},
+			quoted:    true,
+			want: []string{
+				"create infra-a-a.example.com. TXT " + registryValue("infra", "ingress/ns/a"),
+				"create infra-aaaa-a.example.com. TXT " + registryValue("infra", "ingress/ns/a"),
+			},
+		},
+		{
+			name:      "creates a typed TXT record once for several values of a type",
+			hostnames: []kubeHostname{{Hostname: "f.example.com", Resource: "ingress/ns/f"}},
+			want: []string{
+				"create infra-a-f.example.com. TXT heritage=external-dns,external-dns/owner=infra,external-dns/resource=ingress/ns/f",
+			},
+		},
+		{
+			name:      "creates a typed TXT record for every weighted record set",
+			hostnames: []kubeHostname{{Hostname: "g.example.com", Resource: "ingress/ns/g"}},
+			quoted:    true,
+			want: []string{
+				"create infra-a-g.example.com. TXT " + registryValue("infra", "ingress/ns/g"),
+				"create infra-a-g.example.com. TXT " + registryValue("infra", "ingress/ns/g"),
+			},
+		},
+		{
+			name:      "writes unquoted values for Cloudflare",
+			hostnames: []kubeHostname{{Hostname: "d.example.com", Resource: "ingress/ns/d"}},
+			want: []string{
+				"create infra-cname-d.example.com. TXT heritage=external-dns,external-dns/owner=infra,external-dns/resource=ingress/ns/d",
+			},
+		},
+		{
+			name: "leaves out the resource of hostnames declared by several objects",
+			hostnames: []kubeHostname{
+				{Hostname: "d.example.com", Resource: "ingress/ns/d"},
+				{Hostname: "d.example.com", Resource: "service/ns/d"},
+			},
+			quoted: true,
+			want: []string{
+				"create infra-cname-d.example.com. TXT " + registryValue("infra", ""),
+			},
+		},
+		{
+			name:      "skips owned hostnames",
+			hostnames: []kubeHostname{{Hostname: "b.example.com", Resource: "ingress/ns/b"}},
+			quoted:    true,
+		},
+		{
+			name: "skips hostnames without adoptable records",
+			hostnames: []kubeHostname{
+				{Hostname: "c.example.com", Resource: "ingress/ns/c"},
+				{Hostname: "e.example.com", Resource: "ingress/ns/e"},
+			},
+			quoted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := planAdoption(tt.hostnames, records, registry, "infra", tt.quoted)
+			if err != nil {
+				t.Fatalf("planAdoption() error = %v", err)
+			}
+			if got := changeList(changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planAdoption() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
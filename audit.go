@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// auditEntry is a record of an audit report
+type auditEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Owners are the owner IDs found in the TXT records of the record
+	Owners []string `json:"owners,omitempty"`
+	// TXTRecords are the names of the external-DNS TXT records of the record
+	TXTRecords []string `json:"txtRecords,omitempty"`
+	// Resources are the objects of the cluster that declare the hostname
+	Resources []string `json:"resources,omitempty"`
+}
+
+// auditReport classifies the records of a zone by their ownership and whether
+// their hostnames are still found in the cluster
+type auditReport struct {
+	Provider string    `json:"provider"`
+	Zone     string    `json:"zone"`
+	Owner    string    `json:"owner"`
+	Time     time.Time `json:"time"`
+	// OwnedReferenced are records of the owner found in the cluster
+	OwnedReferenced []auditEntry `json:"ownedReferenced"`
+	// OwnedUnreferenced are records of the owner no longer found in the
+	// cluster
+	OwnedUnreferenced []auditEntry `json:"ownedUnreferenced"`
+	// ForeignOwned are records of other owner IDs
+	ForeignOwned []auditEntry `json:"foreignOwned"`
+	// DanglingTXT are external-DNS TXT records without a record they point to
+	DanglingTXT []auditEntry `json:"danglingTXT"`
+	// Unregistered are records without any external-DNS TXT record
+	Unregistered []auditEntry `json:"unregistered"`
+	// MissingDNSEndpoints are the records of DNSEndpoints of the zone domain
+	// that are not found in the zone, if requested
+	MissingDNSEndpoints []auditEntry `json:"missingDNSEndpoints,omitempty"`
+}
+
+// auditZone will classify all the records of the zone and write the report
+// as JSON to path, or to stdout if path is empty. The records of DNSEndpoints
+// missing from the zone are reported too if dnsEndpoints is true.
+func auditZone(provider DNSProvider, kubeClient kubernetes.Interface, dynamicKubeClient dynamic.Interface, registry txtRegistry, owner, path string, dnsEndpoints bool) error {
+	hostnames, err := clusterHostnames(kubeClient, dynamicKubeClient)
+	if err != nil {
+		return err
+	}
+	records, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+
+	report := planAudit(hostnames, records, registry, owner)
+	if dnsEndpoints {
+		domain := zoneDomain(provider, records)
+		if domain == "" {
+			return fmt.Errorf("Cannot find the domain of %s zone: %s to look for missing DNSEndpoint records", provider.Name(), provider.Zone())
+		}
+		report.MissingDNSEndpoints = missingDNSEndpoints(hostnames, records, domain)
+	}
+	report.Provider = provider.Name()
+	report.Zone = provider.Zone()
+	report.Time = time.Now().UTC()
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode audit report: %w", err)
+	}
+	if path == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write audit report: %w", err)
+	}
+	fmt.Printf("Wrote audit report to: %s\n", path)
+	fmt.Printf("Owned and referenced: %d, owned and unreferenced: %d, foreign owned: %d, dangling TXT: %d, unregistered: %d, missing DNSEndpoint records: %d\n",
+		len(report.OwnedReferenced), len(report.OwnedUnreferenced), len(report.ForeignOwned), len(report.DanglingTXT), len(report.Unregistered), len(report.MissingDNSEndpoints))
+	return nil
+}
+
+// planAudit classifies the records by their ownership and whether their
+// hostnames are found in the cluster. External-DNS TXT records are reported
+// together with the record they point to, unless there is none.
+func planAudit(hostnames []kubeHostname, records []Record, registry txtRegistry, owner string) auditReport {
+	report := auditReport{
+		Owner:             owner,
+		OwnedReferenced:   []auditEntry{},
+		OwnedUnreferenced: []auditEntry{},
+		ForeignOwned:      []auditEntry{},
+		DanglingTXT:       []auditEntry{},
+		Unregistered:      []auditEntry{},
+	}
+	resources := hostnameObjects(hostnames)
+
+	// TXT records pointing to a record are reported with it
+	registryTXT := map[string]bool{}
+	for _, record := range records {
+		if record.Type == "TXT" {
+			continue
+		}
+		entry := auditEntry{
+			Name:      record.Name,
+			Type:      record.Type,
+			Resources: resources[record.Name],
+		}
+		txtNames := registry.txtNames(record.Name, record.Type)
+		for _, txt := range records {
+			if txt.Type != "TXT" || !slices.Contains(txtNames, txt.Name) {
+				continue
+			}
+			for _, value := range txt.Values {
+				if o, ok := registry.owner(value); ok && !slices.Contains(entry.Owners, o) {
+					entry.Owners = append(entry.Owners, o)
+				}
+			}
+			if registryTXTRecord(txt, registry) {
+				entry.TXTRecords = append(entry.TXTRecords, txt.Name)
+				registryTXT[txt.Name] = true
+			}
+		}
+		switch {
+		case slices.Contains(entry.Owners, owner) && len(entry.Resources) > 0:
+			report.OwnedReferenced = append(report.OwnedReferenced, entry)
+		case slices.Contains(entry.Owners, owner):
+			report.OwnedUnreferenced = append(report.OwnedUnreferenced, entry)
+		case len(entry.Owners) > 0:
+			report.ForeignOwned = append(report.ForeignOwned, entry)
+		default:
+			report.Unregistered = append(report.Unregistered, entry)
+		}
+	}
+
+	for _, record := range records {
+		if record.Type != "TXT" || registryTXT[record.Name] {
+			continue
+		}
+		entry := auditEntry{Name: record.Name, Type: record.Type}
+		if !registryTXTRecord(record, registry) {
+			report.Unregistered = append(report.Unregistered, entry)
+			continue
+		}
+		for _, value := range record.Values {
+			if o, ok := registry.owner(value); ok && !slices.Contains(entry.Owners, o) {
+				entry.Owners = append(entry.Owners, o)
+			}
+		}
+		report.DanglingTXT = append(report.DanglingTXT, entry)
+	}
+	return report
+}
+
+// registryTXTRecord returns true if any of the TXT record values is an
+// external-DNS registry entry
+func registryTXTRecord(record Record, registry txtRegistry) bool {
+	return slices.ContainsFunc(record.Values, registry.hasHeritage)
+}
+
+// missingDNSEndpoints returns the records of the DNSEndpoints of the domain
+// that are not found in the records
+func missingDNSEndpoints(hostnames []kubeHostname, records []Record, domain string) []auditEntry {
+	missing := []auditEntry{}
+	zone := dnsZone{domain: sanitizeDNSAddress(domain)}
+	for _, h := range hostnames {
+		if h.RecordType == "" || zone.matches(h.Hostname) < 0 {
+			continue
+		}
+		record := Record{Name: sanitizeDNSAddress(h.Hostname), Type: h.RecordType}
+		if findRecord(records, record) >= 0 {
+			continue
+		}
+		if i := slices.IndexFunc(missing, func(e auditEntry) bool { return e.Name == record.Name && e.Type == record.Type }); i >= 0 {
+			missing[i].Resources = append(missing[i].Resources, h.Resource)
+			continue
+		}
+		missing = append(missing, auditEntry{Name: record.Name, Type: record.Type, Resources: []string{h.Resource}})
+	}
+	return missing
+}
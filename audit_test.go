@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// auditNames returns the <name> <type> of the audit entries
+func auditNames(entries []auditEntry) []string {
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name+" "+e.Type)
+	}
+	return names
+}
+
+func TestPlanAudit(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	records := []Record{
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("infra", "ingress/ns/a")),
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-a-b.example.com", "TXT", registryValue("infra", "ingress/ns/b")),
+		testRecord("c.example.com", "CNAME", "lb.example.net."),
+		testRecord("infra-cname-c.example.com", "TXT", registryValue("other", "ingress/ns/c")),
+		testRecord("infra-d.example.com", "TXT", registryValue("infra", "ingress/ns/d")),
+		testRecord("e.example.com", "A", "5.5.5.5"),
+		testRecord("example.com", "TXT", `"v=spf1 -all"`),
+	}
+	hostnames := []kubeHostname{
+		{Hostname: "a.example.com", Resource: "ingress/ns/a"},
+		{Hostname: "c.example.com", Resource: "ingress/ns/c"},
+	}
+
+	report := planAudit(hostnames, records, registry, "infra")
+	tests := []struct {
+		name    string
+		entries []auditEntry
+		want    []string
+	}{
+		{name: "owned and referenced", entries: report.OwnedReferenced, want: []string{"a.example.com. A"}},
+		{name: "owned and unreferenced", entries: report.OwnedUnreferenced, want: []string{"b.example.com. A"}},
+		{name: "foreign owned", entries: report.ForeignOwned, want: []string{"c.example.com. CNAME"}},
+		{name: "dangling TXT", entries: report.DanglingTXT, want: []string{"infra-d.example.com. TXT"}},
+		{name: "unregistered", entries: report.Unregistered, want: []string{"e.example.com. A", "example.com. TXT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditNames(tt.entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planAudit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	want := auditEntry{
+		Name:       "a.example.com.",
+		Type:       "A",
+		Owners:     []string{"infra"},
+		TXTRecords: []string{"infra-a.example.com."},
+		Resources:  []string{"ingress/ns/a"},
+	}
+	if !reflect.DeepEqual(report.OwnedReferenced[0], want) {
+		t.Errorf("planAudit() entry = %+v, want %+v", report.OwnedReferenced[0], want)
+	}
+}
+
+func TestAuditZone(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("infra", "ingress/ns/a")),
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("infra", "ingress/ns/b")),
+	)
+	kubeClient := fake.NewClientset(ingress("ns", "a", nil, "a.example.com"))
+	path := filepath.Join(t.TempDir(), "audit.json")
+
+	if err := auditZone(provider, kubeClient, newFakeDynamicClient(t), registry, "infra", path, false); err != nil {
+		t.Fatalf("auditZone() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var report auditReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	if report.Provider != "memory" || report.Zone != "example.com" {
+		t.Errorf("report is for %s zone: %s, want memory zone: example.com", report.Provider, report.Zone)
+	}
+	if got, want := auditNames(report.OwnedReferenced), []string{"a.example.com. A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("owned and referenced = %q, want %q", got, want)
+	}
+	if got, want := auditNames(report.OwnedUnreferenced), []string{"b.example.com. A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("owned and unreferenced = %q, want %q", got, want)
+	}
+}
@@ -4,14 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
-
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
 )
 
 func newRoute53Client() *route53.Client {
@@ -23,6 +20,182 @@ func newRoute53Client() *route53.Client {
 	return route53.NewFromConfig(cfg)
 }
 
+// route53Provider implements DNSProvider for an AWS Route53 hosted zone
+type route53Provider struct {
+	client *route53.Client
+	zoneID string
+}
+
+func newRoute53Provider(client *route53.Client, zoneID string) *route53Provider {
+	return &route53Provider{
+		client: client,
+		zoneID: zoneID,
+	}
+}
+
+func (p *route53Provider) Name() string {
+	return "aws"
+}
+
+func (p *route53Provider) Zone() string {
+	return p.zoneID
+}
+
+func (p *route53Provider) Records() ([]Record, error) {
+	rrsets, err := route53RecordsList(p.client, p.zoneID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(rrsets))
+	for i, rrset := range rrsets {
+		records[i] = recordFromRoute53(rrset)
+	}
+	return records, nil
+}
+
+func (p *route53Provider) UpdateRecord(record Record, values []string) error {
+	rrset := route53FromRecord(record)
+	return modifyRoute53RecordValue(p.client, p.zoneID, &rrset, values)
+}
+
+func (p *route53Provider) DeleteRecord(record Record) error {
+	return deleteRoute53Record(p.client, p.zoneID, route53FromRecord(record))
+}
+
+func (p *route53Provider) CreateRecord(record Record) error {
+	return createRoute53Record(p.client, p.zoneID, route53FromRecord(record))
+}
+
+// recordFromRoute53 converts a route53 record set to a Record
+func recordFromRoute53(rrset types.ResourceRecordSet) Record {
+	record := Record{
+		Name: aws.ToString(rrset.Name),
+		Type: string(rrset.Type),
+		TTL:  aws.ToInt64(rrset.TTL),
+	}
+	for _, rr := range rrset.ResourceRecords {
+		record.Values = append(record.Values, aws.ToString(rr.Value))
+	}
+	if rrset.AliasTarget != nil {
+		record.Alias = &AliasTarget{
+			DNSName:              aws.ToString(rrset.AliasTarget.DNSName),
+			HostedZoneID:         aws.ToString(rrset.AliasTarget.HostedZoneId),
+			EvaluateTargetHealth: rrset.AliasTarget.EvaluateTargetHealth,
+		}
+	}
+	if rrset.SetIdentifier != nil {
+		record.RoutingPolicy = routingPolicyFromRoute53(rrset)
+	}
+	return record
+}
+
+// routingPolicyFromRoute53 returns the routing policy of a route53 record set
+func routingPolicyFromRoute53(rrset types.ResourceRecordSet) *RoutingPolicy {
+	policy := &RoutingPolicy{
+		SetIdentifier:    aws.ToString(rrset.SetIdentifier),
+		Weight:           rrset.Weight,
+		Region:           string(rrset.Region),
+		Failover:         string(rrset.Failover),
+		MultiValueAnswer: rrset.MultiValueAnswer,
+		HealthCheckID:    aws.ToString(rrset.HealthCheckId),
+	}
+	if l := rrset.GeoLocation; l != nil {
+		policy.GeoLocation = &GeoLocation{
+			ContinentCode:   aws.ToString(l.ContinentCode),
+			CountryCode:     aws.ToString(l.CountryCode),
+			SubdivisionCode: aws.ToString(l.SubdivisionCode),
+		}
+	}
+	if l := rrset.GeoProximityLocation; l != nil {
+		policy.GeoProximityLocation = &GeoProximityLocation{
+			AWSRegion:      aws.ToString(l.AWSRegion),
+			LocalZoneGroup: aws.ToString(l.LocalZoneGroup),
+			Bias:           l.Bias,
+		}
+		if l.Coordinates != nil {
+			policy.GeoProximityLocation.Latitude = aws.ToString(l.Coordinates.Latitude)
+			policy.GeoProximityLocation.Longitude = aws.ToString(l.Coordinates.Longitude)
+		}
+	}
+	if c := rrset.CidrRoutingConfig; c != nil {
+		policy.CIDRRoutingConfig = &CIDRRoutingConfig{
+			CollectionID: aws.ToString(c.CollectionId),
+			LocationName: aws.ToString(c.LocationName),
+		}
+	}
+	return policy
+}
+
+// route53FromRecord converts a Record to a route53 record set
+func route53FromRecord(record Record) types.ResourceRecordSet {
+	rrset := types.ResourceRecordSet{
+		Name: aws.String(record.Name),
+		Type: types.RRType(record.Type),
+	}
+	if record.RoutingPolicy != nil {
+		setRoute53RoutingPolicy(&rrset, record.RoutingPolicy)
+	}
+	if record.Alias != nil {
+		rrset.AliasTarget = &types.AliasTarget{
+			DNSName:              aws.String(record.Alias.DNSName),
+			HostedZoneId:         aws.String(record.Alias.HostedZoneID),
+			EvaluateTargetHealth: record.Alias.EvaluateTargetHealth,
+		}
+		return rrset
+	}
+	rrset.TTL = aws.Int64(record.TTL)
+	for _, value := range record.Values {
+		rrset.ResourceRecords = append(rrset.ResourceRecords, types.ResourceRecord{Value: aws.String(value)})
+	}
+	return rrset
+}
+
+// setRoute53RoutingPolicy sets the routing policy of a route53 record set
+func setRoute53RoutingPolicy(rrset *types.ResourceRecordSet, policy *RoutingPolicy) {
+	rrset.SetIdentifier = aws.String(policy.SetIdentifier)
+	rrset.Weight = policy.Weight
+	rrset.Region = types.ResourceRecordSetRegion(policy.Region)
+	rrset.Failover = types.ResourceRecordSetFailover(policy.Failover)
+	rrset.MultiValueAnswer = policy.MultiValueAnswer
+	if policy.HealthCheckID != "" {
+		rrset.HealthCheckId = aws.String(policy.HealthCheckID)
+	}
+	if l := policy.GeoLocation; l != nil {
+		rrset.GeoLocation = &types.GeoLocation{
+			ContinentCode:   optionalString(l.ContinentCode),
+			CountryCode:     optionalString(l.CountryCode),
+			SubdivisionCode: optionalString(l.SubdivisionCode),
+		}
+	}
+	if l := policy.GeoProximityLocation; l != nil {
+		rrset.GeoProximityLocation = &types.GeoProximityLocation{
+			AWSRegion:      optionalString(l.AWSRegion),
+			LocalZoneGroup: optionalString(l.LocalZoneGroup),
+			Bias:           l.Bias,
+		}
+		if l.Latitude != "" || l.Longitude != "" {
+			rrset.GeoProximityLocation.Coordinates = &types.Coordinates{
+				Latitude:  aws.String(l.Latitude),
+				Longitude: aws.String(l.Longitude),
+			}
+		}
+	}
+	if c := policy.CIDRRoutingConfig; c != nil {
+		rrset.CidrRoutingConfig = &types.CidrRoutingConfig{
+			CollectionId: aws.String(c.CollectionID),
+			LocationName: aws.String(c.LocationName),
+		}
+	}
+}
+
+// optionalString returns a pointer to the string, or nil if it is empty
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
 func route53RecordsList(client *route53.Client, zoneID string) ([]types.ResourceRecordSet, error) {
 	var allRecords []types.ResourceRecordSet
 	var nextRecordName *string
@@ -58,16 +231,14 @@ func modifyRoute53RecordValue(client *route53.Client, zoneID string, record *typ
 		resourceRecords = append(resourceRecords, types.ResourceRecord{Value: &value})
 	}
 
+	// Keep the routing policy of the record set, which identifies it
+	rrset := *record
+	rrset.ResourceRecords = resourceRecords
 	changeBatch := &types.ChangeBatch{
 		Changes: []types.Change{
 			{
-				Action: types.ChangeActionUpsert, // Update the existing record or create it if it doesn’t exist
-				ResourceRecordSet: &types.ResourceRecordSet{
-					Name:            record.Name,
-					Type:            record.Type,
-					TTL:             record.TTL,
-					ResourceRecords: resourceRecords,
-				},
+				Action:            types.ChangeActionUpsert, // Update the existing record or create it if it doesn’t exist
+				ResourceRecordSet: &rrset,
 			},
 		},
 	}
@@ -83,9 +254,9 @@ func modifyRoute53RecordValue(client *route53.Client, zoneID string, record *typ
 	return nil
 }
 
-func deleteRoute53Record(client *route53.Client, zoneID string, record types.ResourceRecordSet) error {
+func createRoute53Record(client *route53.Client, zoneID string, record types.ResourceRecordSet) error {
 	change := types.Change{
-		Action:            types.ChangeActionDelete,
+		Action:            types.ChangeActionCreate,
 		ResourceRecordSet: &record,
 	}
 
@@ -98,169 +269,27 @@ func deleteRoute53Record(client *route53.Client, zoneID string, record types.Res
 
 	_, err := client.ChangeResourceRecordSets(context.TODO(), input)
 	if err != nil {
-		return fmt.Errorf("failed to delete record: %w", err)
-	}
-	return nil
-}
-
-func migrateAWSRoute53Owner(client *route53.Client, kubeClient *kubernetes.Clientset, prefix, oldOwner, newOwner, zoneID string, dryRun bool) error {
-	hostnames, err := externalDNSKubeHostnames(kubeClient)
-	if err != nil {
-		return err
-	}
-
-	records, err := route53RecordsList(client, zoneID)
-	if err != nil {
-		return fmt.Errorf("Cannot list records in aws zone with ID: %s, %v", zoneID, err)
-	}
-	for _, hostname := range hostnames {
-		for _, r := range lookupExternalDNSRoute53TXTRecords(hostname, prefix, records) {
-			var newValues []string
-			for _, rr := range r.ResourceRecords {
-				if verifyOwner(*rr.Value, oldOwner) {
-					v, err := replaceOwner(*rr.Value, newOwner)
-					if err != nil {
-						return err
-					}
-					newValues = append(newValues, v)
-				}
-			}
-			if len(newValues) == 0 {
-				continue
-			}
-			msg := fmt.Sprintf("Updating record: %s Type: %s with values: %s", *r.Name, string(r.Type), newValues)
-			if dryRun {
-				msg += " (dry run)"
-			}
-			fmt.Println(msg)
-			if !dryRun {
-				if err := modifyRoute53RecordValue(client, zoneID, &r, newValues); err != nil {
-					log.Printf("Failed to update record: %v", err)
-				}
-			}
-		}
+		return fmt.Errorf("failed to create record: %w", err)
 	}
 	return nil
 }
 
-func deleteAWSRoute53OwnerRecords(client *route53.Client, kubeClient *kubernetes.Clientset, dynamiKubeClient *dynamic.DynamicClient, prefix, owner, zoneID string, dryRun bool) error {
-	ingressHostnames, err := allIngressHosts(kubeClient)
-	if err != nil {
-		return fmt.Errorf("Cannot list Ingresses: %v", err)
+func deleteRoute53Record(client *route53.Client, zoneID string, record types.ResourceRecordSet) error {
+	change := types.Change{
+		Action:            types.ChangeActionDelete,
+		ResourceRecordSet: &record,
 	}
 
-	ingressRoutes, err := ingressRouteList(dynamiKubeClient)
-	if err != nil {
-		return fmt.Errorf("Cannot list IngressRoute hosts: %v", err)
-	}
-	ingressRouteHostnames, err := extractHostnamesFromIngressRoutes(ingressRoutes)
-	if err != nil {
-		return fmt.Errorf("Cannot extract hostnames from ingress routes")
-	}
-	serviceHostnames, err := externalDNSServiceHostnames(kubeClient)
-	if err != nil {
-		return fmt.Errorf("Cannot list Services: %v", err)
+	input := &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: &zoneID,
+		ChangeBatch: &types.ChangeBatch{
+			Changes: []types.Change{change},
+		},
 	}
 
-	allRecords, err := route53RecordsList(client, zoneID)
+	_, err := client.ChangeResourceRecordSets(context.TODO(), input)
 	if err != nil {
-		return fmt.Errorf("Cannot list records in aws zone with ID: %s, %v", zoneID, err)
-	}
-
-	toDeleteRecords := ownedRoute53RecordsList(allRecords, prefix, owner)
-	for _, record := range toDeleteRecords {
-		if record.Type == "TXT" {
-			continue
-		}
-		// Skip if the record is still found in Ingress resources of the cluster
-		if addressInList(*record.Name, ingressHostnames) {
-			fmt.Printf("Skipping record: %s found in Ingress rules hosts\n", *record.Name)
-			continue
-		}
-		// Skip if the record is still found in an IngressRoute host
-		if addressInList(*record.Name, ingressRouteHostnames) {
-			fmt.Printf("Skipping record: %s found in IngressRoute rule hosts\n", *record.Name)
-			continue
-		}
-		// Skip if the record is still found as a hostname annotation in a Service
-		if addressInList(*record.Name, serviceHostnames) {
-			fmt.Printf("Skipping record: %s found in Service as external-DNS hostname link\n", *record.Name)
-			continue
-		}
-		// Delete record
-		msg := fmt.Sprintf("Deleting record: %s Type: %s", *record.Name, record.Type)
-		if dryRun {
-			msg += " (dry run)"
-		}
-		fmt.Println(msg)
-		if !dryRun {
-			deleteRoute53Record(client, zoneID, record)
-		}
-		// Delete TXT ownership records
-		for _, txt := range lookupExternalDNSRoute53TXTRecords(*record.Name, prefix, allRecords) {
-			msg := fmt.Sprintf("Deleting record: %s Type: %s", *txt.Name, txt.Type)
-			if dryRun {
-				msg += " (dry run)"
-			}
-			fmt.Println(msg)
-			if !dryRun {
-				deleteRoute53Record(client, zoneID, txt)
-			}
-		}
+		return fmt.Errorf("failed to delete record: %w", err)
 	}
 	return nil
 }
-
-// ownedRoute53RecordsList expects a list of route53 records, a prefix and an
-// owner ID and will return a list of records, including TXT ones, that belong
-// to the owner ID.
-func ownedRoute53RecordsList(records []types.ResourceRecordSet, prefix, owner string) []types.ResourceRecordSet {
-	ownedRecords := []types.ResourceRecordSet{}
-	for _, record := range records {
-		if record.Type == "TXT" {
-			continue
-		}
-		owned := false
-		for _, r := range lookupExternalDNSRoute53TXTRecords(*record.Name, prefix, records) {
-			for _, rr := range r.ResourceRecords {
-				if verifyOwner(*rr.Value, owner) {
-					owned = true
-					break
-				}
-			}
-		}
-		if owned {
-			ownedRecords = append(ownedRecords, record)
-		}
-
-	}
-	return ownedRecords
-}
-
-// lookupExternalDNSRoute53TXTRecords returns all the TXT records found for a hostname
-func lookupExternalDNSRoute53TXTRecords(hostname, prefix string, records []types.ResourceRecordSet) []types.ResourceRecordSet {
-	var externalDNSRecords []types.ResourceRecordSet
-	found, record := verifyRoute53Record(hostname, records)
-	if !found {
-		return externalDNSRecords
-	}
-	txtRecord := fmt.Sprintf("%s-%s", prefix, sanitizeDNSAddress(hostname))
-	txtTypeRecord := fmt.Sprintf("%s-%s-%s", prefix, strings.ToLower(string(record.Type)), sanitizeDNSAddress(hostname))
-	for _, record := range records {
-		if (txtRecord == *record.Name || txtTypeRecord == *record.Name) && record.Type == "TXT" {
-			externalDNSRecords = append(externalDNSRecords, record)
-		}
-	}
-	return externalDNSRecords
-}
-
-// verifyRoute53Record verifies that a hostname is in the passed list of records and
-// returns the respective record
-func verifyRoute53Record(hostname string, records []types.ResourceRecordSet) (bool, types.ResourceRecordSet) {
-	for _, record := range records {
-		if *record.Name == sanitizeDNSAddress(hostname) {
-			return true, record
-		}
-	}
-	return false, types.ResourceRecordSet{}
-}
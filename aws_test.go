@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func TestRoute53RecordConversion(t *testing.T) {
+	tests := []struct {
+		name  string
+		rrset types.ResourceRecordSet
+	}{
+		{
+			name: "simple record set",
+			rrset: types.ResourceRecordSet{
+				Name:            aws.String("a.example.com."),
+				Type:            types.RRTypeA,
+				TTL:             aws.Int64(300),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("1.1.1.1")}},
+			},
+		},
+		{
+			name: "alias record set",
+			rrset: types.ResourceRecordSet{
+				Name: aws.String("example.com."),
+				Type: types.RRTypeA,
+				AliasTarget: &types.AliasTarget{
+					DNSName:              aws.String("lb.eu-west-1.elb.amazonaws.com."),
+					HostedZoneId:         aws.String("Z32O12XQLNTSW2"),
+					EvaluateTargetHealth: true,
+				},
+			},
+		},
+		{
+			name: "weighted record set",
+			rrset: types.ResourceRecordSet{
+				Name:            aws.String("a.example.com."),
+				Type:            types.RRTypeA,
+				TTL:             aws.Int64(300),
+				SetIdentifier:   aws.String("blue"),
+				Weight:          aws.Int64(0),
+				HealthCheckId:   aws.String("abcdef11-2222-3333-4444-555555fedcba"),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("1.1.1.1")}},
+			},
+		},
+		{
+			name: "failover record set",
+			rrset: types.ResourceRecordSet{
+				Name:            aws.String("a.example.com."),
+				Type:            types.RRTypeA,
+				TTL:             aws.Int64(300),
+				SetIdentifier:   aws.String("primary"),
+				Failover:        types.ResourceRecordSetFailoverPrimary,
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("1.1.1.1")}},
+			},
+		},
+		{
+			name: "latency and multivalue answer record sets",
+			rrset: types.ResourceRecordSet{
+				Name:             aws.String("a.example.com."),
+				Type:             types.RRTypeA,
+				TTL:              aws.Int64(300),
+				SetIdentifier:    aws.String("eu"),
+				Region:           types.ResourceRecordSetRegionEuWest1,
+				MultiValueAnswer: aws.Bool(true),
+				ResourceRecords:  []types.ResourceRecord{{Value: aws.String("1.1.1.1")}},
+			},
+		},
+		{
+			name: "geolocation record set",
+			rrset: types.ResourceRecordSet{
+				Name:            aws.String("a.example.com."),
+				Type:            types.RRTypeA,
+				TTL:             aws.Int64(300),
+				SetIdentifier:   aws.String("gb"),
+				GeoLocation:     &types.GeoLocation{CountryCode: aws.String("GB")},
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("1.1.1.1")}},
+			},
+		},
+		{
+			name: "geoproximity record set",
+			rrset: types.ResourceRecordSet{
+				Name:          aws.String("a.example.com."),
+				Type:          types.RRTypeA,
+				TTL:           aws.Int64(300),
+				SetIdentifier: aws.String("london"),
+				GeoProximityLocation: &types.GeoProximityLocation{
+					Coordinates: &types.Coordinates{Latitude: aws.String("51.50"), Longitude: aws.String("-0.12")},
+					Bias:        aws.Int32(10),
+				},
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("1.1.1.1")}},
+			},
+		},
+		{
+			name: "IP-based record set",
+			rrset: types.ResourceRecordSet{
+				Name:          aws.String("a.example.com."),
+				Type:          types.RRTypeA,
+				TTL:           aws.Int64(300),
+				SetIdentifier: aws.String("office"),
+				CidrRoutingConfig: &types.CidrRoutingConfig{
+					CollectionId: aws.String("c8c02a84-aaaa-bbbb-e0d2-d833a2f80106"),
+					LocationName: aws.String("office"),
+				},
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("1.1.1.1")}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := route53FromRecord(recordFromRoute53(tt.rrset)); !reflect.DeepEqual(got, tt.rrset) {
+				t.Errorf("route53FromRecord(recordFromRoute53()) = %+v, want %+v", got, tt.rrset)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// snapshot is a copy of all the records of a zone at a point in time
+type snapshot struct {
+	Provider string    `json:"provider"`
+	Zone     string    `json:"zone"`
+	Time     time.Time `json:"time"`
+	Records  []Record  `json:"records"`
+}
+
+// writeSnapshot writes the records of a zone to a new snapshot file in dir and
+// returns its path.
+func writeSnapshot(dir string, provider DNSProvider, records []Record) (string, error) {
+	s := snapshot{
+		Provider: provider.Name(),
+		Zone:     provider.Zone(),
+		Time:     time.Now().UTC(),
+		Records:  records,
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	f, err := createBackupFile(dir, provider, s.Time, "snapshot.json")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return f.Name(), f.Close()
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	var s snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// createBackupFile creates a new file in dir named after the provider zone and
+// time, ending with the passed extension. A counter is added to the name if a
+// file with the same name already exists.
+func createBackupFile(dir string, provider DNSProvider, t time.Time, ext string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	zone := strings.NewReplacer("/", "_", string(filepath.Separator), "_").Replace(provider.Zone())
+	name := fmt.Sprintf("%s-%s-%s", provider.Name(), zone, t.Format("20060102T150405Z"))
+	for i := 0; ; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s", name, ext))
+		if i > 0 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d.%s", name, i, ext))
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backup file: %w", err)
+		}
+		return f, nil
+	}
+}
+
+// restoreSnapshot will put the external-DNS records of a zone back to the
+// state recorded in a snapshot file. Records missing from the zone are created
+// again and records whose values differ are updated. Records created after the
+// snapshot was taken are left untouched.
+func restoreSnapshot(provider DNSProvider, path string, registry txtRegistry, opts runOptions) error {
+	s, err := readSnapshot(path)
+	if err != nil {
+		return err
+	}
+	if s.Provider != provider.Name() || s.Zone != provider.Zone() {
+		return fmt.Errorf("snapshot is for %s zone: %s, not %s zone: %s", s.Provider, s.Zone, provider.Name(), provider.Zone())
+	}
+	records, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+	return executeChanges(provider, records, planRestore(s.Records, records, registry, recordPerValue(provider)), opts)
+}
+
+// planRestore returns the changes needed to bring the external-DNS records of
+// a zone back to their snapshot state. External-DNS records are the TXT
+// ownership records and the records they point to. perValue is set for
+// providers that store one record per value.
+func planRestore(snapshotRecords, records []Record, registry txtRegistry, perValue bool) []change {
+	var changes []change
+	for _, record := range externalDNSRecordsList(snapshotRecords, registry) {
+		want := record
+		want.ID = ""
+		i := matchRecord(records, record, perValue)
+		if i < 0 {
+			changes = append(changes, createChange(record.Name, want))
+			continue
+		}
+		if !recordsEqual(records[i], record) {
+			changes = append(changes, updateChange(record.Name, records[i], record.Values))
+		}
+	}
+	return changes
+}
+
+// externalDNSRecordsList returns the TXT records that carry the external-DNS
+// heritage and the records they hold ownership information for.
+func externalDNSRecordsList(records []Record, registry txtRegistry) []Record {
+	var externalDNSRecords []Record
+	for _, record := range records {
+		if record.Type == "TXT" {
+			for _, value := range record.Values {
+				if registry.hasHeritage(value) {
+					externalDNSRecords = append(externalDNSRecords, record)
+					break
+				}
+			}
+			continue
+		}
+		if len(lookupExternalDNSTXTRecords(record.Name, registry, records)) > 0 {
+			externalDNSRecords = append(externalDNSRecords, record)
+		}
+	}
+	return externalDNSRecords
+}
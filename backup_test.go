@@ -0,0 +1,141 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// withID returns the record with the passed ID
+func withID(record Record, id string) Record {
+	record.ID = id
+	return record
+}
+
+func TestPlanRestore(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	snapshot := []Record{
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "")),
+		testRecord("b.example.com", "CNAME", "lb.example.net."),
+		testRecord("infra-b.example.com", "TXT", registryValue("old", "")),
+		testRecord("example.com", "MX", "10 mx.example.com."),
+	}
+	// Cloudflare lists a record for every value
+	cloudflareSnapshot := []Record{
+		withID(testRecord("a.example.com", "A", "1.1.1.1"), "a"),
+		withID(testRecord("a.example.com", "A", "2.2.2.2"), "b"),
+		withID(testRecord("infra-a.example.com", "TXT", registryValue("old", "")), "c"),
+	}
+	tests := []struct {
+		name     string
+		snapshot []Record
+		records  []Record
+		perValue bool
+		want     []string
+	}{
+		{
+			name:     "leaves unchanged records",
+			snapshot: snapshot,
+			records:  snapshot,
+		},
+		{
+			name:     "creates deleted records",
+			snapshot: snapshot,
+			records:  snapshot[2:],
+			want: []string{
+				"create a.example.com. A 1.1.1.1",
+				"create infra-a.example.com. TXT " + registryValue("old", ""),
+			},
+		},
+		{
+			name:     "updates changed records",
+			snapshot: snapshot,
+			records: []Record{
+				snapshot[0],
+				testRecord("infra-a.example.com", "TXT", registryValue("new", "")),
+				testRecord("b.example.com", "CNAME", "other.example.net."),
+				snapshot[3],
+			},
+			want: []string{
+				"update infra-a.example.com. TXT " + registryValue("old", ""),
+				"update b.example.com. CNAME lb.example.net.",
+			},
+		},
+		{
+			name:     "ignores records that are not managed by external-dns",
+			snapshot: snapshot,
+			records:  append(append([]Record(nil), snapshot[:4]...), testRecord("example.com", "MX", "20 mx.example.com.")),
+		},
+		{
+			name:     "leaves unchanged records of every value",
+			snapshot: cloudflareSnapshot,
+			records:  cloudflareSnapshot,
+			perValue: true,
+		},
+		{
+			name:     "creates the deleted record of a value",
+			snapshot: cloudflareSnapshot,
+			records:  []Record{cloudflareSnapshot[0], cloudflareSnapshot[2]},
+			perValue: true,
+			want: []string{
+				"create a.example.com. A 2.2.2.2",
+			},
+		},
+		{
+			name:     "leaves records of a value created again with another ID",
+			snapshot: cloudflareSnapshot,
+			records:  []Record{withID(cloudflareSnapshot[1], "d"), cloudflareSnapshot[0], cloudflareSnapshot[2]},
+			perValue: true,
+		},
+		{
+			name:     "updates the changed record of a value by ID",
+			snapshot: cloudflareSnapshot,
+			records:  []Record{cloudflareSnapshot[0], withID(testRecord("a.example.com", "A", "3.3.3.3"), "b"), cloudflareSnapshot[2]},
+			perValue: true,
+			want: []string{
+				"update a.example.com. #b A 2.2.2.2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changeList(planRestore(tt.snapshot, tt.records, registry, tt.perValue))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planRestore() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("old", "ingress/ns/b")),
+	)
+	want := zoneRecords(t, provider)
+	records, _ := provider.Records()
+	dir := t.TempDir()
+	path, err := writeSnapshot(dir, provider, records)
+	if err != nil {
+		t.Fatalf("writeSnapshot() error = %v", err)
+	}
+
+	hostnames := []kubeHostname{{Hostname: "a.example.com"}}
+	migration := ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"}
+	if err := migrateOwner(provider, hostnames, registry, migration, runOptions{backupDir: dir}); err != nil {
+		t.Fatalf("migrateOwner() error = %v", err)
+	}
+	if err := provider.DeleteRecord(records[2]); err != nil {
+		t.Fatalf("DeleteRecord() error = %v", err)
+	}
+
+	if err := restoreSnapshot(provider, path, registry, runOptions{backupDir: dir}); err != nil {
+		t.Fatalf("restoreSnapshot() error = %v", err)
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
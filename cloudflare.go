@@ -3,18 +3,101 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/cloudflare/cloudflare-go"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
 )
 
 func newCloudflareAPIClient(key, email string) (*cloudflare.API, error) {
 	return cloudflare.New(key, email)
 }
 
+// cloudflareProvider implements DNSProvider for a Cloudflare zone
+type cloudflareProvider struct {
+	api      *cloudflare.API
+	zoneName string
+}
+
+func newCloudflareProvider(api *cloudflare.API, zoneName string) *cloudflareProvider {
+	return &cloudflareProvider{
+		api:      api,
+		zoneName: zoneName,
+	}
+}
+
+func (p *cloudflareProvider) Name() string {
+	return "cloudflare"
+}
+
+func (p *cloudflareProvider) Zone() string {
+	return p.zoneName
+}
+
+func (p *cloudflareProvider) Records() ([]Record, error) {
+	cfRecords, err := cloudflareRecordsList(p.api, p.zoneName)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(cfRecords))
+	for i, r := range cfRecords {
+		records[i] = recordFromCloudflare(r)
+	}
+	return records, nil
+}
+
+// UpdateRecord updates the content of a Cloudflare record. Cloudflare records
+// hold a single value, so exactly one value is expected.
+func (p *cloudflareProvider) UpdateRecord(record Record, values []string) error {
+	if len(values) != 1 {
+		return fmt.Errorf("cloudflare records hold a single value, got: %d", len(values))
+	}
+	return modifyCloudflareDNSRecord(p.api, p.zoneName, cloudflareFromRecord(record), values[0])
+}
+
+func (p *cloudflareProvider) DeleteRecord(record Record) error {
+	return deleteCloudflareDNSRecord(p.api, p.zoneName, cloudflareFromRecord(record))
+}
+
+// CreateRecord creates a Cloudflare record for each one of the record values
+func (p *cloudflareProvider) CreateRecord(record Record) error {
+	for _, value := range record.Values {
+		r := cloudflareFromRecord(record)
+		r.Content = value
+		if err := createCloudflareDNSRecord(p.api, p.zoneName, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordFromCloudflare converts a Cloudflare record to a Record
+func recordFromCloudflare(r cloudflare.DNSRecord) Record {
+	return Record{
+		Name:    sanitizeDNSAddress(r.Name),
+		Type:    r.Type,
+		TTL:     int64(r.TTL),
+		Values:  []string{r.Content},
+		ID:      r.ID,
+		Proxied: r.Proxied,
+	}
+}
+
+// cloudflareFromRecord converts a Record to a Cloudflare record. Only the
+// first value of the record is used as the record content.
+func cloudflareFromRecord(record Record) cloudflare.DNSRecord {
+	r := cloudflare.DNSRecord{
+		ID:      record.ID,
+		Name:    strings.TrimSuffix(record.Name, "."),
+		Type:    record.Type,
+		TTL:     int(record.TTL),
+		Proxied: record.Proxied,
+	}
+	if len(record.Values) > 0 {
+		r.Content = record.Values[0]
+	}
+	return r
+}
+
 // ListCloudflareRecords lists all DNS records for a given zone in a Cloudflare account.
 func cloudflareRecordsList(api *cloudflare.API, zoneName string) ([]cloudflare.DNSRecord, error) {
 	zoneID, err := api.ZoneIDByName(zoneName)
@@ -50,168 +133,31 @@ func modifyCloudflareDNSRecord(api *cloudflare.API, zoneName string, record clou
 	return nil
 }
 
-func deleteCloudflareDNSRecord(api *cloudflare.API, zoneName string, record cloudflare.DNSRecord) error {
-	zoneID, err := api.ZoneIDByName(zoneName)
-	if err != nil {
-		return fmt.Errorf("failed to fetch zone ID: %w", err)
+func createCloudflareDNSRecord(api *cloudflare.API, zoneName string, record cloudflare.DNSRecord) error {
+	newRecord := cloudflare.CreateDNSRecordParams{
+		Type:    record.Type,
+		Name:    record.Name,
+		Content: record.Content,
+		TTL:     record.TTL,
+		Proxied: record.Proxied,
 	}
 
-	return api.DeleteDNSRecord(context.Background(), cloudflare.ZoneIdentifier(zoneID), record.ID)
-}
-
-func migrateCloudflareRecordOwner(api *cloudflare.API, kubeClient *kubernetes.Clientset, prefix, oldOwner, newOwner, zoneName string, dryRun bool) error {
-	hostnames, err := externalDNSKubeHostnames(kubeClient)
+	zoneID, err := api.ZoneIDByName(zoneName)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to fetch zone ID: %w", err)
 	}
-	records, err := cloudflareRecordsList(api, zoneName)
+	_, err = api.CreateDNSRecord(context.Background(), cloudflare.ZoneIdentifier(zoneID), newRecord)
 	if err != nil {
-		return fmt.Errorf("Cannot list records in cloudfare zone named: %s, %v", zoneName, err)
-	}
-	for _, hostname := range hostnames {
-		for _, record := range lookupExternalDNSCloudflareTXTRecords(hostname, prefix, records) {
-			var newContent string
-			if verifyOwner(record.Content, oldOwner) {
-				v, err := replaceOwner(record.Content, newOwner)
-				if err != nil {
-					return err
-				}
-				newContent = v
-			}
-			if newContent == "" {
-				continue
-			}
-			msg := fmt.Sprintf("Updating record: %s Type: %s with values: %s", record.Name, record.Type, newContent)
-			if dryRun {
-				msg += " (dry run)"
-			}
-			fmt.Println(msg)
-			if !dryRun {
-				if err := modifyCloudflareDNSRecord(api, zoneName, record, newContent); err != nil {
-					log.Printf("Failed to update record: %v", err)
-				}
-			}
-
-		}
+		return fmt.Errorf("failed to create DNS record: %w", err)
 	}
 	return nil
 }
 
-func deleteCloudflareOwnerRecords(api *cloudflare.API, kubeClient *kubernetes.Clientset, dynamiKubeClient *dynamic.DynamicClient, prefix, owner, zoneName string, dryRun bool) error {
-	ingressHostnames, err := allIngressHosts(kubeClient)
-	if err != nil {
-		return fmt.Errorf("Cannot list Ingresses: %v", err)
-	}
-
-	ingressRoutes, err := ingressRouteList(dynamiKubeClient)
-	if err != nil {
-		return fmt.Errorf("Cannot list IngressRoute hosts: %v", err)
-	}
-	ingressRouteHostnames, err := extractHostnamesFromIngressRoutes(ingressRoutes)
-	if err != nil {
-		return fmt.Errorf("Cannot extract hostnames from ingress routes")
-	}
-	serviceHostnames, err := externalDNSServiceHostnames(kubeClient)
-	if err != nil {
-		return fmt.Errorf("Cannot list Services: %v", err)
-	}
-
-	allRecords, err := cloudflareRecordsList(api, zoneName)
+func deleteCloudflareDNSRecord(api *cloudflare.API, zoneName string, record cloudflare.DNSRecord) error {
+	zoneID, err := api.ZoneIDByName(zoneName)
 	if err != nil {
-		return fmt.Errorf("Cannot list records in cloudflare zone: %s, %v", zoneName, err)
-	}
-
-	toDeleteRecords := ownedCloudflareRecordsList(allRecords, prefix, owner)
-	for _, record := range toDeleteRecords {
-		if record.Type == "TXT" {
-			continue
-		}
-		// Skip if the record is still found in Ingress resources of the cluster
-		if addressInList(record.Name, ingressHostnames) {
-			fmt.Printf("Skipping record: %s found in Ingress rules hosts\n", record.Name)
-			continue
-		}
-		// Skip if the record is still found in an IngressRoute host
-		if addressInList(record.Name, ingressRouteHostnames) {
-			fmt.Printf("Skipping record: %s found in IngressRoute rule hosts\n", record.Name)
-			continue
-		}
-		// Skip if the record is still found as a hostname annotation in a Service
-		if addressInList(record.Name, serviceHostnames) {
-			fmt.Printf("Skipping record: %s found in Service as external-DNS hostname link\n", record.Name)
-			continue
-		}
-		// Delete record
-		msg := fmt.Sprintf("Deleting record: %s Type: %s", record.Name, record.Type)
-		if dryRun {
-			msg += " (dry run)"
-		}
-		fmt.Println(msg)
-		if !dryRun {
-			deleteCloudflareDNSRecord(api, zoneName, record)
-		}
-		// Delete TXT ownership records
-		for _, txt := range lookupExternalDNSCloudflareTXTRecords(record.Name, prefix, allRecords) {
-			msg := fmt.Sprintf("Deleting record: %s Type: %s", txt.Name, txt.Type)
-			if dryRun {
-				msg += " (dry run)"
-			}
-			fmt.Println(msg)
-			if !dryRun {
-				deleteCloudflareDNSRecord(api, zoneName, txt)
-			}
-		}
-	}
-	return nil
-}
-
-// ownedCloudflareRecordsList expects a list of route53 records, a prefix and an
-// owner ID and will return a list of records, including TXT ones, that belong
-// to the owner ID.
-func ownedCloudflareRecordsList(records []cloudflare.DNSRecord, prefix, owner string) []cloudflare.DNSRecord {
-	ownedRecords := []cloudflare.DNSRecord{}
-	for _, record := range records {
-		if record.Type == "TXT" {
-			continue
-		}
-		owned := false
-		for _, r := range lookupExternalDNSCloudflareTXTRecords(record.Name, prefix, records) {
-			if verifyOwner(r.Content, owner) {
-				owned = true
-				break
-			}
-		}
-		if owned {
-			ownedRecords = append(ownedRecords, record)
-		}
-
-	}
-	return ownedRecords
-}
-
-func lookupExternalDNSCloudflareTXTRecords(hostname, prefix string, records []cloudflare.DNSRecord) []cloudflare.DNSRecord {
-	var externalDNSRecords []cloudflare.DNSRecord
-	found, record := verifyCloudflareRecord(hostname, records)
-	if !found {
-		return externalDNSRecords
-	}
-	txtRecord := fmt.Sprintf("%s-%s", prefix, sanitizeDNSAddress(hostname))
-	txtTypeRecord := fmt.Sprintf("%s-%s-%s", prefix, strings.ToLower(string(record.Type)), sanitizeDNSAddress(hostname))
-	for _, record := range records {
-		if (txtRecord == sanitizeDNSAddress(record.Name) || txtTypeRecord == sanitizeDNSAddress(record.Name)) && record.Type == "TXT" {
-			externalDNSRecords = append(externalDNSRecords, record)
-		}
+		return fmt.Errorf("failed to fetch zone ID: %w", err)
 	}
-	return externalDNSRecords
-}
 
-// verifyCloudflareRecord verifies that a hostname is in the passed list of records and
-// returns the respective record
-func verifyCloudflareRecord(hostname string, records []cloudflare.DNSRecord) (bool, cloudflare.DNSRecord) {
-	for _, record := range records {
-		if sanitizeDNSAddress(record.Name) == sanitizeDNSAddress(hostname) {
-			return true, record
-		}
-	}
-	return false, cloudflare.DNSRecord{}
+	return api.DeleteDNSRecord(context.Background(), cloudflare.ZoneIdentifier(zoneID), record.ID)
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// HTTPProxy GVR (GroupVersionResource) for Contour
+var httpProxyGVR = schema.GroupVersionResource{
+	Group:    "projectcontour.io",
+	Version:  "v1",
+	Resource: "httpproxies",
+}
+
+// contourIngressClassAnnotation is the Contour annotation that sets the class
+// of HTTPProxies
+const contourIngressClassAnnotation = "projectcontour.io/ingress.class"
+
+// httpProxyHostnames returns the virtual host FQDNs of all the Contour
+// HTTPProxies. HTTPProxies are skipped in clusters where Contour is not
+// installed.
+func httpProxyHostnames(client dynamic.Interface) ([]kubeHostname, error) {
+	var hostnames []kubeHostname
+	httpProxies, err := customResourceList(client, httpProxyGVR)
+	if err != nil {
+		return nil, err
+	}
+	for _, httpProxy := range httpProxies {
+		fqdn, _, _ := unstructured.NestedString(httpProxy.Object, "spec", "virtualhost", "fqdn")
+		if fqdn == "" {
+			continue
+		}
+		// external-dns labels the records of HTTPProxies with the kind as is
+		h := customResourceHostname(httpProxy, "HTTPProxy", fqdn)
+		h.IngressClass, _, _ = unstructured.NestedString(httpProxy.Object, "spec", "ingressClassName")
+		if h.IngressClass == "" {
+			h.IngressClass = httpProxy.GetAnnotations()[contourIngressClassAnnotation]
+		}
+		hostnames = append(hostnames, h)
+	}
+	return hostnames, nil
+}
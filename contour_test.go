@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHTTPProxyHostnames(t *testing.T) {
+	annotated := customResource(httpProxyGVR, "HTTPProxy", "ns", "b", map[string]interface{}{
+		"virtualhost": map[string]interface{}{"fqdn": "b.example.com"},
+	})
+	annotated.obj.SetAnnotations(map[string]string{contourIngressClassAnnotation: "internal"})
+	client := newFakeDynamicClient(t,
+		customResource(httpProxyGVR, "HTTPProxy", "ns", "a", map[string]interface{}{
+			"ingressClassName": "public",
+			"virtualhost":      map[string]interface{}{"fqdn": "a.example.com"},
+		}),
+		annotated,
+		// Included by another HTTPProxy, without a virtual host
+		customResource(httpProxyGVR, "HTTPProxy", "ns", "c", map[string]interface{}{
+			"routes": []interface{}{map[string]interface{}{}},
+		}),
+	)
+	hostnames, err := httpProxyHostnames(client)
+	if err != nil {
+		t.Fatalf("httpProxyHostnames() error = %v", err)
+	}
+	want := []kubeHostname{
+		{Hostname: "a.example.com", Resource: "HTTPProxy/ns/a", Namespace: "ns", IngressClass: "public"},
+		{Hostname: "b.example.com", Resource: "HTTPProxy/ns/b", Namespace: "ns", IngressClass: "internal"},
+	}
+	if !reflect.DeepEqual(hostnames, want) {
+		t.Errorf("httpProxyHostnames() = %+v, want %+v", hostnames, want)
+	}
+}
+
+func TestHTTPProxyHostnamesNotInstalled(t *testing.T) {
+	client := newFakeDynamicClient(t)
+	notServed(client, httpProxyGVR.Group, httpProxyGVR.Version)
+	hostnames, err := httpProxyHostnames(client)
+	if err != nil {
+		t.Fatalf("httpProxyHostnames() error = %v", err)
+	}
+	if len(hostnames) != 0 {
+		t.Errorf("httpProxyHostnames() = %v, want none", hostnames)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// defaultTTL is the TTL of copied records that do not carry one the target
+// provider accepts, like Route53 alias records and Cloudflare records with an
+// automatic TTL
+const defaultTTL = 300
+
+// copyOwnerRecords will copy the records of the owner ID, together with their
+// external-DNS TXT records, from the source zone to the target one. The owner
+// of the copied TXT records is rewritten to newOwner, if set.
+func copyOwnerRecords(source, target DNSProvider, registry txtRegistry, owner, newOwner string, opts runOptions) error {
+	sourceRecords, err := source.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", source.Name(), source.Zone(), err)
+	}
+	targetRecords, err := target.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", target.Name(), target.Zone(), err)
+	}
+	changes, err := planCopy(source.Name(), sourceRecords, target.Name(), targetRecords, registry, owner, newOwner, quotedTXT(target))
+	if err != nil {
+		return err
+	}
+	return executeChanges(target, targetRecords, changes, opts)
+}
+
+// planCopy returns the changes needed to create the records of the owner ID
+// and their TXT records in the target zone. Records that already exist in the
+// target zone are skipped.
+func planCopy(sourceProvider string, sourceRecords []Record, targetProvider string, targetRecords []Record, registry txtRegistry, owner, newOwner string, quoted bool) ([]change, error) {
+	var changes []change
+	var planned []Record
+	exists := func(records []Record, record Record) bool {
+		return findRecord(records, Record{Name: record.Name, Type: record.Type, RoutingPolicy: record.RoutingPolicy}) >= 0
+	}
+
+	// Route53 alias records are flattened to CNAME records, which Cloud DNS
+	// does not allow at the zone apex
+	var apex string
+	if i := slices.IndexFunc(sourceRecords, func(r Record) bool { return r.Type == "SOA" }); i >= 0 {
+		apex = sourceRecords[i].Name
+	}
+
+	for _, record := range mergeRecordSets(ownedRecordsList(sourceRecords, registry, owner)) {
+		if record.Alias != nil && record.Name == apex && targetProvider != "aws" && targetProvider != "cloudflare" {
+			fmt.Printf("Skipping alias record: %s Type: %s at the zone apex, %s zones do not allow CNAME records at the apex\n", record.Name, record.Type, targetProvider)
+			continue
+		}
+		// Only Route53 zones can hold several record sets of the same name
+		// and type
+		if record.RoutingPolicy != nil && targetProvider != "aws" {
+			fmt.Printf("Skipping record: %s Type: %s with set identifier: %s, %s zones do not support Route53 routing policies\n", record.Name, record.Type, record.RoutingPolicy.SetIdentifier, targetProvider)
+			continue
+		}
+		translated := translateRecord(record, sourceProvider, targetProvider)
+		// Alias records of different types are flattened to the same CNAME
+		if exists(planned, translated) {
+			continue
+		}
+		if exists(targetRecords, translated) {
+			fmt.Printf("Skipping record: %s Type: %s already exists in %s zone\n", translated.Name, translated.Type, targetProvider)
+			continue
+		}
+		changes = append(changes, createChange(record.Name, translated))
+		planned = append(planned, translated)
+
+		// Copy the TXT ownership records, named after the type of the
+		// translated record
+		for _, name := range registry.txtNames(record.Name, record.Type) {
+			i := findRecord(sourceRecords, Record{Name: name, Type: "TXT", RoutingPolicy: record.RoutingPolicy})
+			if i < 0 {
+				continue
+			}
+			txt := sourceRecords[i]
+			copied := Record{Name: txt.Name, Type: "TXT", TTL: translated.TTL, RoutingPolicy: translated.RoutingPolicy}
+			if txt.Name == sanitizeDNSAddress(registry.typedTXTName(record.Name, record.Type)) {
+				copied.Name = sanitizeDNSAddress(registry.typedTXTName(record.Name, translated.Type))
+			}
+			// Legacy TXT records are shared by all the types of a hostname
+			if exists(planned, copied) {
+				continue
+			}
+			if exists(targetRecords, copied) {
+				fmt.Printf("Skipping record: %s Type: TXT already exists in %s zone\n", copied.Name, targetProvider)
+				continue
+			}
+			for _, value := range txt.Values {
+				l, format, err := registry.parseValue(value)
+				if err != nil {
+					continue
+				}
+				if newOwner != "" {
+					l[labelOwner] = newOwner
+				}
+				format.quoted = quoted
+				v, err := registry.formatValue(l, format)
+				if err != nil {
+					return nil, fmt.Errorf("Cannot copy record: %s, %v", txt.Name, err)
+				}
+				copied.Values = append(copied.Values, v)
+			}
+			changes = append(changes, createChange(record.Name, copied))
+			planned = append(planned, copied)
+		}
+	}
+	return changes, nil
+}
+
+// mergeRecordSets merges the records of the same name and type into a single
+// record set. Cloudflare lists a record for every value.
+func mergeRecordSets(records []Record) []Record {
+	var merged []Record
+	for _, record := range records {
+		if i := findRecord(merged, Record{Name: record.Name, Type: record.Type, RoutingPolicy: record.RoutingPolicy}); i >= 0 {
+			merged[i].Values = append(merged[i].Values, record.Values...)
+			continue
+		}
+		r := copyRecord(record)
+		r.ID = ""
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// translateRecord converts a record of the source provider to an equivalent
+// record of the target provider. Route53 alias records are flattened to CNAME
+// records, CNAME targets are written in the format of the target provider and
+// the Cloudflare proxy status is dropped for other providers.
+func translateRecord(record Record, sourceProvider, targetProvider string) Record {
+	r := copyRecord(record)
+	r.ID = ""
+	if r.Alias != nil && targetProvider != "aws" {
+		fmt.Printf("Translating alias record: %s Type: %s to CNAME: %s\n", r.Name, r.Type, r.Alias.DNSName)
+		r.Type = "CNAME"
+		r.Values = []string{r.Alias.DNSName}
+		r.Alias = nil
+	}
+	// Cloudflare stores CNAME targets without the trailing dot, which Route53
+	// and Cloud DNS expect
+	if r.Type == "CNAME" {
+		for i, value := range r.Values {
+			if targetProvider == "cloudflare" {
+				r.Values[i] = strings.TrimSuffix(value, ".")
+			} else if targetProvider == "aws" || targetProvider == "gcp" {
+				r.Values[i] = sanitizeDNSAddress(value)
+			}
+		}
+	}
+	if r.Proxied != nil && targetProvider != "cloudflare" {
+		if *r.Proxied {
+			fmt.Printf("Record: %s is proxied by Cloudflare, its copy will resolve to the origin: %v\n", r.Name, r.Values)
+		}
+		r.Proxied = nil
+	}
+	// Route53 alias records have no TTL and Cloudflare uses 1 for automatic
+	if (r.TTL == 0 && r.Alias == nil) || (sourceProvider == "cloudflare" && targetProvider != "cloudflare" && r.TTL == 1) {
+		r.TTL = defaultTTL
+	}
+	return r
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslateRecord(t *testing.T) {
+	proxied := true
+	alias := &AliasTarget{DNSName: "lb-1.eu-west-1.elb.amazonaws.com.", HostedZoneID: "Z32O12XQLNTSW2"}
+	tests := []struct {
+		name   string
+		record Record
+		source string
+		target string
+		want   Record
+	}{
+		{
+			name:   "alias record to CNAME",
+			record: Record{Name: "a.example.com.", Type: "A", Alias: alias},
+			source: "aws",
+			target: "gcp",
+			want:   Record{Name: "a.example.com.", Type: "CNAME", TTL: defaultTTL, Values: []string{"lb-1.eu-west-1.elb.amazonaws.com."}},
+		},
+		{
+			name:   "alias record to Cloudflare CNAME",
+			record: Record{Name: "a.example.com.", Type: "A", Alias: alias},
+			source: "aws",
+			target: "cloudflare",
+			want:   Record{Name: "a.example.com.", Type: "CNAME", TTL: defaultTTL, Values: []string{"lb-1.eu-west-1.elb.amazonaws.com"}},
+		},
+		{
+			name:   "alias record between Route53 zones",
+			record: Record{Name: "a.example.com.", Type: "A", Alias: alias},
+			source: "aws",
+			target: "aws",
+			want:   Record{Name: "a.example.com.", Type: "A", Alias: alias},
+		},
+		{
+			name:   "Cloudflare CNAME to Cloud DNS",
+			record: Record{Name: "a.example.com.", Type: "CNAME", TTL: 1, Values: []string{"lb.example.net"}, ID: "a", Proxied: &proxied},
+			source: "cloudflare",
+			target: "gcp",
+			want:   Record{Name: "a.example.com.", Type: "CNAME", TTL: defaultTTL, Values: []string{"lb.example.net."}},
+		},
+		{
+			name:   "Cloudflare CNAME to Route53",
+			record: Record{Name: "a.example.com.", Type: "CNAME", TTL: 120, Values: []string{"lb.example.net"}},
+			source: "cloudflare",
+			target: "aws",
+			want:   Record{Name: "a.example.com.", Type: "CNAME", TTL: 120, Values: []string{"lb.example.net."}},
+		},
+		{
+			name:   "Cloud DNS CNAME to Cloudflare",
+			record: Record{Name: "a.example.com.", Type: "CNAME", TTL: 300, Values: []string{"lb.example.net."}},
+			source: "gcp",
+			target: "cloudflare",
+			want:   Record{Name: "a.example.com.", Type: "CNAME", TTL: 300, Values: []string{"lb.example.net"}},
+		},
+		{
+			name:   "proxied record between Cloudflare zones",
+			record: Record{Name: "a.example.com.", Type: "A", TTL: 1, Values: []string{"1.1.1.1"}, ID: "a", Proxied: &proxied},
+			source: "cloudflare",
+			target: "cloudflare",
+			want:   Record{Name: "a.example.com.", Type: "A", TTL: 1, Values: []string{"1.1.1.1"}, Proxied: &proxied},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translateRecord(tt.record, tt.source, tt.target); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("translateRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanCopy(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	// Cloudflare lists a record for every value and stores TXT values
+	// unquoted
+	cloudflareRecords := []Record{
+		withID(testRecord("a.example.com", "A", "1.1.1.1"), "1"),
+		withID(testRecord("a.example.com", "A", "2.2.2.2"), "2"),
+		withID(testRecord("infra-a.example.com", "TXT", "heritage=external-dns,external-dns/owner=old"), "3"),
+		withID(testRecord("infra-a-a.example.com", "TXT", "heritage=external-dns,external-dns/owner=old"), "4"),
+		withID(testRecord("b.example.com", "CNAME", "lb.example.net"), "5"),
+		withID(testRecord("infra-cname-b.example.com", "TXT", "heritage=external-dns,external-dns/owner=old"), "6"),
+		withID(testRecord("c.example.com", "A", "3.3.3.3"), "7"),
+		withID(testRecord("infra-c.example.com", "TXT", "heritage=external-dns,external-dns/owner=other"), "8"),
+	}
+	route53Records := []Record{
+		{Name: "a.example.com.", Type: "A", Alias: &AliasTarget{DNSName: "lb-1.elb.amazonaws.com.", HostedZoneID: "Z1"}},
+		{Name: "a.example.com.", Type: "AAAA", Alias: &AliasTarget{DNSName: "lb-1.elb.amazonaws.com.", HostedZoneID: "Z1"}},
+		testRecord("infra-a-a.example.com", "TXT", registryValue("old", "")),
+		testRecord("infra-aaaa-a.example.com", "TXT", registryValue("old", "")),
+	}
+	apexRecords := []Record{
+		{Name: "example.com.", Type: "SOA", TTL: 900, Values: []string{"ns-1.awsdns-1.org. hostmaster.example.com. 1 7200 900 1209600 86400"}},
+		{Name: "example.com.", Type: "A", Alias: &AliasTarget{DNSName: "lb-1.elb.amazonaws.com.", HostedZoneID: "Z1"}},
+		testRecord("infra-a-example.com", "TXT", registryValue("old", "")),
+		{Name: "www.example.com.", Type: "A", Alias: &AliasTarget{DNSName: "lb-1.elb.amazonaws.com.", HostedZoneID: "Z1"}},
+		testRecord("infra-a-www.example.com", "TXT", registryValue("old", "")),
+	}
+	weightedRecords := []Record{
+		withSetIdentifier(testRecord("a.example.com", "A", "1.1.1.1"), "blue"),
+		withSetIdentifier(testRecord("a.example.com", "A", "2.2.2.2"), "green"),
+		withSetIdentifier(testRecord("infra-a-a.example.com", "TXT", registryValue("old", "ingress/ns/blue")), "blue"),
+		withSetIdentifier(testRecord("infra-a-a.example.com", "TXT", registryValue("old", "ingress/ns/green")), "green"),
+	}
+	tests := []struct {
+		name           string
+		sourceProvider string
+		sourceRecords  []Record
+		targetProvider string
+		targetRecords  []Record
+		newOwner       string
+		quoted         bool
+		want           []string
+	}{
+		{
+			name:           "Cloudflare to Cloud DNS",
+			sourceProvider: "cloudflare",
+			sourceRecords:  cloudflareRecords,
+			targetProvider: "gcp",
+			newOwner:       "new",
+			quoted:         true,
+			want: []string{
+				"create a.example.com. A 1.1.1.1 2.2.2.2",
+				"create infra-a.example.com. TXT " + registryValue("new", ""),
+				"create infra-a-a.example.com. TXT " + registryValue("new", ""),
+				"create b.example.com. CNAME lb.example.net.",
+				"create infra-cname-b.example.com. TXT " + registryValue("new", ""),
+			},
+		},
+		{
+			name:           "records that exist in the target zone are skipped",
+			sourceProvider: "cloudflare",
+			sourceRecords:  cloudflareRecords,
+			targetProvider: "gcp",
+			targetRecords: []Record{
+				testRecord("a.example.com", "A", "9.9.9.9"),
+				testRecord("infra-cname-b.example.com", "TXT", registryValue("other", "")),
+			},
+			quoted: true,
+			want: []string{
+				"create b.example.com. CNAME lb.example.net.",
+			},
+		},
+		{
+			name:           "Route53 alias records to Cloudflare",
+			sourceProvider: "aws",
+			sourceRecords:  route53Records,
+			targetProvider: "cloudflare",
+			want: []string{
+				"create a.example.com. CNAME lb-1.elb.amazonaws.com",
+				"create infra-cname-a.example.com. TXT heritage=external-dns,external-dns/owner=old",
+			},
+		},
+		{
+			name:           "Route53 alias records at the apex to Cloud DNS are skipped",
+			sourceProvider: "aws",
+			sourceRecords:  apexRecords,
+			targetProvider: "gcp",
+			quoted:         true,
+			want: []string{
+				"create www.example.com. CNAME lb-1.elb.amazonaws.com.",
+				"create infra-cname-www.example.com. TXT " + registryValue("old", ""),
+			},
+		},
+		{
+			name:           "Route53 alias records at the apex to Cloudflare",
+			sourceProvider: "aws",
+			sourceRecords:  apexRecords,
+			targetProvider: "cloudflare",
+			want: []string{
+				"create example.com. CNAME lb-1.elb.amazonaws.com",
+				"create infra-cname-example.com. TXT heritage=external-dns,external-dns/owner=old",
+				"create www.example.com. CNAME lb-1.elb.amazonaws.com",
+				"create infra-cname-www.example.com. TXT heritage=external-dns,external-dns/owner=old",
+			},
+		},
+		{
+			name:           "weighted record sets between Route53 zones",
+			sourceProvider: "aws",
+			sourceRecords:  weightedRecords,
+			targetProvider: "aws",
+			quoted:         true,
+			want: []string{
+				"create a.example.com. A 1.1.1.1",
+				"create infra-a-a.example.com. TXT " + registryValue("old", "ingress/ns/blue"),
+				"create a.example.com. A 2.2.2.2",
+				"create infra-a-a.example.com. TXT " + registryValue("old", "ingress/ns/green"),
+			},
+		},
+		{
+			name:           "weighted record sets to Cloud DNS are skipped",
+			sourceProvider: "aws",
+			sourceRecords:  weightedRecords,
+			targetProvider: "gcp",
+			quoted:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := planCopy(tt.sourceProvider, tt.sourceRecords, tt.targetProvider, tt.targetRecords, registry, "old", tt.newOwner, tt.quoted)
+			if err != nil {
+				t.Fatalf("planCopy() error = %v", err)
+			}
+			if got := changeList(changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planCopy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyOwnerRecords(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	source := newMemoryProvider("source.example.com",
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("other", "ingress/ns/b")),
+	)
+	target := newMemoryProvider("target.example.com")
+
+	if err := copyOwnerRecords(source, target, registry, "old", "new", runOptions{backupDir: t.TempDir()}); err != nil {
+		t.Fatalf("copyOwnerRecords() error = %v", err)
+	}
+	want := map[string][]string{
+		"a.example.com. A":         {"1.1.1.1"},
+		"infra-a.example.com. TXT": {registryValue("new", "ingress/ns/a")},
+	}
+	if got := zoneRecords(t, target); !reflect.DeepEqual(got, want) {
+		t.Errorf("target zone records = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// The TXT registry encryption of external-dns (--txt-encrypt-enabled)
+// compresses the registry text with gzip and encrypts it with AES-GCM, using a
+// 12 byte nonce that is prepended to the cipher text. The result is base64
+// encoded.
+const gcmNonceSize = 12
+
+// parseAESKey accepts a 32 byte AES key either as it is or base64 encoded, like
+// external-dns --txt-encrypt-aes-key does.
+func parseAESKey(key string) ([]byte, error) {
+	if len(key) == 32 {
+		return []byte(key), nil
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+		decoded, err := encoding.DecodeString(key)
+		if err == nil && len(decoded) == 32 {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("the AES key must be 32 bytes long, either as it is or base64 encoded")
+}
+
+// encryptText compresses and encrypts the text with the AES key, using a new
+// random nonce
+func encryptText(text string, aesKey []byte) (string, error) {
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	data, err := compressData([]byte(text))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, data, nil)), nil
+}
+
+// decryptText decrypts and decompresses a text encrypted with encryptText
+func decryptText(text string, aesKey []byte) (string, error) {
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode text: %w", err)
+	}
+	if len(data) <= gcmNonceSize {
+		return "", fmt.Errorf("encrypted text is too short")
+	}
+	nonce, cipherText := data[:gcmNonceSize], data[gcmNonceSize:]
+	plainData, err := gcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt text: %w", err)
+	}
+	plainData, err = decompressData(plainData)
+	if err != nil {
+		return "", err
+	}
+	return string(plainData), nil
+}
+
+func newGCM(aesKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, gcmNonceSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+func compressData(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	if _, err := gz.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func decompressData(data []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	}
+	defer gz.Close()
+	var b bytes.Buffer
+	if _, err := b.ReadFrom(gz); err != nil {
+		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	}
+	return b.Bytes(), nil
+}
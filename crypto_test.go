@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const (
+	testAESKey    = "0123456789abcdef0123456789abcdef"
+	testNewAESKey = "fedcba9876543210fedcba9876543210"
+)
+
+func TestParseAESKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "raw", key: testAESKey},
+		{name: "standard base64", key: base64.StdEncoding.EncodeToString([]byte(testAESKey))},
+		{name: "URL base64", key: base64.URLEncoding.EncodeToString([]byte(testAESKey))},
+		{name: "too short", key: "0123456789abcdef", wantErr: true},
+		{name: "base64 of a short key", key: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseAESKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAESKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(key) != testAESKey {
+				t.Errorf("parseAESKey() = %q, want %q", key, testAESKey)
+			}
+		})
+	}
+}
+
+func TestEncryptTextRoundTrip(t *testing.T) {
+	text := "heritage=external-dns,external-dns/owner=infra,external-dns/resource=ingress/ns/a"
+	encrypted, err := encryptText(text, []byte(testAESKey))
+	if err != nil {
+		t.Fatalf("encryptText() error = %v", err)
+	}
+	if strings.Contains(encrypted, "heritage") {
+		t.Fatalf("encryptText() = %s, want an encrypted text", encrypted)
+	}
+	decrypted, err := decryptText(encrypted, []byte(testAESKey))
+	if err != nil {
+		t.Fatalf("decryptText() error = %v", err)
+	}
+	if decrypted != text {
+		t.Errorf("decryptText() = %s, want %s", decrypted, text)
+	}
+
+	// Every encryption uses a new nonce
+	again, err := encryptText(text, []byte(testAESKey))
+	if err != nil {
+		t.Fatalf("encryptText() error = %v", err)
+	}
+	if again == encrypted {
+		t.Error("encryptText() returned the same cipher text twice")
+	}
+	if _, err := decryptText(encrypted, []byte(testNewAESKey)); err == nil {
+		t.Error("decryptText() error = nil with another key")
+	}
+	if _, err := decryptText("heritage=external-dns", []byte(testAESKey)); err == nil {
+		t.Error("decryptText() error = nil for a plain text")
+	}
+}
+
+func TestEncryptedRegistryValues(t *testing.T) {
+	registry, err := newTXTRegistry("infra", "", testAESKey, "")
+	if err != nil {
+		t.Fatalf("newTXTRegistry() error = %v", err)
+	}
+	value, err := registry.formatValue(labels{labelOwner: "old"}, txtFormat{quoted: true, encrypted: true})
+	if err != nil {
+		t.Fatalf("formatValue() error = %v", err)
+	}
+	if owner, ok := registry.owner(value); !ok || owner != "old" {
+		t.Fatalf("owner() = %s, %v, want old, true", owner, ok)
+	}
+
+	rewritten, changed, err := registry.rewriteLabels(value, func(l labels) { l[labelOwner] = "new" })
+	if err != nil || !changed {
+		t.Fatalf("rewriteLabels() = %v, %v, want a rewritten value", changed, err)
+	}
+	l, format, err := registry.parseValue(rewritten)
+	if err != nil {
+		t.Fatalf("parseValue() error = %v", err)
+	}
+	if !format.encrypted || !format.quoted || l[labelOwner] != "new" {
+		t.Errorf("parseValue() = %v, %+v, want an encrypted and quoted value of owner new", l, format)
+	}
+
+	// Plain values are still read by registries with a key
+	if owner, ok := registry.owner(registryValue("plain", "")); !ok || owner != "plain" {
+		t.Errorf("owner() = %s, %v, want plain, true", owner, ok)
+	}
+}
+
+func TestNewTXTRegistryKeys(t *testing.T) {
+	if _, err := newTXTRegistry("infra", "", "", testNewAESKey); err == nil {
+		t.Error("newTXTRegistry() error = nil for a new key without the current one")
+	}
+	if _, err := newTXTRegistry("infra", "", "short", ""); err == nil {
+		t.Error("newTXTRegistry() error = nil for an invalid key")
+	}
+}
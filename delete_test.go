@@ -0,0 +1,251 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeleteOwnerRecords(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		// Found in the cluster in an Ingress
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		// Found in the cluster in an IngressRoute
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("old", "ingressroute/ns/b")),
+		// No longer found in the cluster
+		testRecord("c.example.com", "CNAME", "lb.example.net."),
+		testRecord("infra-c.example.com", "TXT", registryValue("old", "ingress/ns/c")),
+		testRecord("infra-cname-c.example.com", "TXT", registryValue("old", "ingress/ns/c")),
+		// Owned by another owner ID
+		testRecord("d.example.com", "A", "4.4.4.4"),
+		testRecord("infra-d.example.com", "TXT", registryValue("other", "ingress/ns/d")),
+	)
+	kubeClient := fake.NewClientset(
+		ingress("ns", "a", nil, "a.example.com"),
+	)
+	dynamicKubeClient := newFakeDynamicClient(t,
+		customResource(ingressRouteGVR, "IngressRoute", "ns", "b", map[string]interface{}{
+			"routes": []interface{}{
+				map[string]interface{}{"match": "Host(`b.example.com`)"},
+			},
+		}),
+	)
+
+	if err := deleteOwnerRecords(provider, kubeClient, dynamicKubeClient, registry, "old", runOptions{backupDir: t.TempDir()}); err != nil {
+		t.Fatalf("deleteOwnerRecords() error = %v", err)
+	}
+	want := map[string][]string{
+		"a.example.com. A":         {"1.1.1.1"},
+		"infra-a.example.com. TXT": {registryValue("old", "ingress/ns/a")},
+		"b.example.com. A":         {"2.2.2.2"},
+		"infra-b.example.com. TXT": {registryValue("old", "ingressroute/ns/b")},
+		"d.example.com. A":         {"4.4.4.4"},
+		"infra-d.example.com. TXT": {registryValue("other", "ingress/ns/d")},
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
+
+// cloudflareMemoryProvider is a memoryProvider that stands for a Cloudflare
+// zone, which holds one record per value
+type cloudflareMemoryProvider struct {
+	*memoryProvider
+}
+
+func (p cloudflareMemoryProvider) Name() string {
+	return "cloudflare"
+}
+
+func TestDeleteOwnerRecordsSeveralRecords(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	tests := []struct {
+		name     string
+		provider func() DNSProvider
+	}{
+		{
+			name: "dual-stack hostname",
+			provider: func() DNSProvider {
+				return newMemoryProvider("example.com",
+					testRecord("c.example.com", "A", "3.3.3.3"),
+					testRecord("c.example.com", "AAAA", "::3"),
+					testRecord("infra-a-c.example.com", "TXT", registryValue("old", "ingress/ns/c")),
+					testRecord("infra-aaaa-c.example.com", "TXT", registryValue("old", "ingress/ns/c")),
+					testRecord("d.example.com", "A", "4.4.4.4"),
+				)
+			},
+		},
+		{
+			name: "Cloudflare hostname with several values",
+			provider: func() DNSProvider {
+				return cloudflareMemoryProvider{newMemoryProvider("example.com",
+					testRecord("c.example.com", "A", "3.3.3.3"),
+					testRecord("c.example.com", "A", "3.3.3.4"),
+					testRecord("infra-c.example.com", "TXT", "heritage=external-dns,external-dns/owner=old"),
+					testRecord("infra-a-c.example.com", "TXT", "heritage=external-dns,external-dns/owner=old"),
+					testRecord("d.example.com", "A", "4.4.4.4"),
+				)}
+			},
+		},
+	}
+	want := map[string][]string{
+		"d.example.com. A": {"4.4.4.4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := tt.provider()
+			if err := deleteOwnerRecords(provider, fake.NewClientset(), newFakeDynamicClient(t), registry, "old", runOptions{backupDir: t.TempDir()}); err != nil {
+				t.Fatalf("deleteOwnerRecords() error = %v", err)
+			}
+			if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+				t.Errorf("zone records = %v, want %v", got, want)
+			}
+		})
+		t.Run(tt.name+" from a plan", func(t *testing.T) {
+			provider := tt.provider()
+			path := filepath.Join(t.TempDir(), "plan.json")
+			if err := deleteOwnerRecords(provider, fake.NewClientset(), newFakeDynamicClient(t), registry, "old", runOptions{planPath: path}); err != nil {
+				t.Fatalf("deleteOwnerRecords() error = %v", err)
+			}
+			if err := applyPlan(provider, path, runOptions{backupDir: t.TempDir()}); err != nil {
+				t.Fatalf("applyPlan() error = %v", err)
+			}
+			if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+				t.Errorf("zone records = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDeleteOwnerRecordsDryRun(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		testRecord("c.example.com", "A", "3.3.3.3"),
+		testRecord("infra-c.example.com", "TXT", registryValue("old", "ingress/ns/c")),
+	)
+	want := zoneRecords(t, provider)
+	if err := deleteOwnerRecords(provider, fake.NewClientset(), newFakeDynamicClient(t), registry, "old", runOptions{dryRun: true}); err != nil {
+		t.Fatalf("deleteOwnerRecords() error = %v", err)
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
+
+func TestOwnedRecordsList(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	records := []Record{
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "")),
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("other", "")),
+		testRecord("c.example.com", "A", "3.3.3.3"),
+		// The zone apex is managed by external-dns
+		testRecord("example.com", "SOA", "ns-1.awsdns-1.org. hostmaster.example.com. 1 7200 900 1209600 86400"),
+		testRecord("example.com", "NS", "ns-1.awsdns-1.org."),
+		testRecord("example.com", "A", "4.4.4.4"),
+		testRecord("infra-example.com", "TXT", registryValue("old", "")),
+	}
+	var got []string
+	for _, record := range ownedRecordsList(records, registry, "old") {
+		got = append(got, record.Name+" "+record.Type)
+	}
+	if want := []string{"a.example.com. A", "example.com. A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ownedRecordsList() = %v, want %v", got, want)
+	}
+}
+
+func TestDanglingTXTRecords(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	records := []Record{
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("infra", "")),
+		testRecord("infra-a-a.example.com", "TXT", registryValue("infra", "")),
+		// The CNAME record of the hostname was deleted
+		testRecord("infra-cname-a.example.com", "TXT", registryValue("infra", "")),
+		testRecord("infra-b.example.com", "TXT", registryValue("infra", "")),
+		testRecord("infra-c.example.com", "TXT", registryValue("other", "")),
+		// Not an external-dns registry record
+		testRecord("example.com", "TXT", `"v=spf1 -all"`),
+	}
+	var got []string
+	for _, record := range danglingTXTRecords(records, registry) {
+		got = append(got, record.Name)
+	}
+	want := []string{"infra-cname-a.example.com.", "infra-b.example.com.", "infra-c.example.com."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("danglingTXTRecords() = %v, want %v", got, want)
+	}
+}
+
+func TestRemainingRecords(t *testing.T) {
+	records := []Record{
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("a.example.com", "AAAA", "::1"),
+		testRecord("infra-aaaa-a.example.com", "TXT", registryValue("old", "")),
+		withID(testRecord("b.example.com", "A", "2.2.2.2"), "1"),
+		withID(testRecord("b.example.com", "A", "2.2.2.3"), "2"),
+	}
+	tests := []struct {
+		name     string
+		changes  []change
+		perValue bool
+		want     []string
+	}{
+		{
+			name: "drops the deleted records",
+			changes: []change{
+				deleteChange("a.example.com", records[1]),
+				updateChange("a.example.com", records[2], []string{registryValue("new", "")}),
+			},
+			want: []string{"a.example.com. A", "infra-aaaa-a.example.com. TXT", "b.example.com. A", "b.example.com. A"},
+		},
+		{
+			name:     "drops the deleted values of per-value providers",
+			changes:  []change{deleteChange("b.example.com", records[4])},
+			perValue: true,
+			want:     []string{"a.example.com. A", "a.example.com. AAAA", "infra-aaaa-a.example.com. TXT", "b.example.com. A"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, record := range remainingRecords(records, tt.changes, tt.perValue) {
+				got = append(got, record.Name+" "+record.Type)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remainingRecords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteOwnerRecordsDanglingTXT(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		// Left behind by a deleted record
+		testRecord("infra-b.example.com", "TXT", registryValue("old", "ingress/ns/b")),
+		testRecord("infra-a-b.example.com", "TXT", registryValue("old", "ingress/ns/b")),
+		// The hostname is still found in the cluster
+		testRecord("infra-a-c.example.com", "TXT", registryValue("old", "ingress/ns/c")),
+		// Owned by another owner ID
+		testRecord("infra-d.example.com", "TXT", registryValue("other", "ingress/ns/d")),
+	)
+	kubeClient := fake.NewClientset(ingress("ns", "c", nil, "c.example.com"))
+
+	if err := deleteOwnerRecords(provider, kubeClient, newFakeDynamicClient(t), registry, "old", runOptions{backupDir: t.TempDir()}); err != nil {
+		t.Fatalf("deleteOwnerRecords() error = %v", err)
+	}
+	want := map[string][]string{
+		"infra-a-c.example.com. TXT": {registryValue("old", "ingress/ns/c")},
+		"infra-d.example.com. TXT":   {registryValue("other", "ingress/ns/d")},
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
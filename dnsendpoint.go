@@ -0,0 +1,42 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// DNSEndpoint GVR (GroupVersionResource) of the external-dns crd source
+var dnsEndpointGVR = schema.GroupVersionResource{
+	Group:    "externaldns.k8s.io",
+	Version:  "v1alpha1",
+	Resource: "dnsendpoints",
+}
+
+// dnsEndpointHostnames returns the DNS names of the endpoints of all the
+// DNSEndpoints, together with their record types. DNSEndpoints are skipped in
+// clusters where the CRD is not installed.
+func dnsEndpointHostnames(client dynamic.Interface) ([]kubeHostname, error) {
+	var hostnames []kubeHostname
+	dnsEndpoints, err := customResourceList(client, dnsEndpointGVR)
+	if err != nil {
+		return nil, err
+	}
+	for _, dnsEndpoint := range dnsEndpoints {
+		endpoints, _, _ := unstructured.NestedSlice(dnsEndpoint.Object, "spec", "endpoints")
+		for _, endpoint := range endpoints {
+			endpointMap, ok := endpoint.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			dnsName, _ := endpointMap["dnsName"].(string)
+			if dnsName == "" {
+				continue
+			}
+			h := customResourceHostname(dnsEndpoint, "crd", dnsName)
+			h.RecordType, _ = endpointMap["recordType"].(string)
+			hostnames = append(hostnames, h)
+		}
+	}
+	return hostnames, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDNSEndpointHostnames(t *testing.T) {
+	client := newFakeDynamicClient(t,
+		customResource(dnsEndpointGVR, "DNSEndpoint", "ns", "records", map[string]interface{}{
+			"endpoints": []interface{}{
+				map[string]interface{}{"dnsName": "a.example.com", "recordType": "A", "targets": []interface{}{"1.1.1.1"}},
+				map[string]interface{}{"dnsName": "b.example.com", "recordType": "CNAME", "targets": []interface{}{"lb.example.net"}},
+				map[string]interface{}{"recordType": "A", "targets": []interface{}{"2.2.2.2"}},
+			},
+		}),
+	)
+	hostnames, err := dnsEndpointHostnames(client)
+	if err != nil {
+		t.Fatalf("dnsEndpointHostnames() error = %v", err)
+	}
+	want := []kubeHostname{
+		{Hostname: "a.example.com", Resource: "crd/ns/records", Namespace: "ns", RecordType: "A"},
+		{Hostname: "b.example.com", Resource: "crd/ns/records", Namespace: "ns", RecordType: "CNAME"},
+	}
+	if !reflect.DeepEqual(hostnames, want) {
+		t.Errorf("dnsEndpointHostnames() = %+v, want %+v", hostnames, want)
+	}
+}
+
+func TestMissingDNSEndpoints(t *testing.T) {
+	records := []Record{
+		testRecord("example.com", "SOA", "ns.example.com. hostmaster.example.com. 1 7200 900 1209600 86400"),
+		testRecord("a.example.com", "A", "1.1.1.1"),
+	}
+	hostnames := []kubeHostname{
+		{Hostname: "a.example.com", Resource: "crd/ns/a", RecordType: "A"},
+		// Found with another type
+		{Hostname: "a.example.com", Resource: "crd/ns/a", RecordType: "AAAA"},
+		{Hostname: "b.example.com", Resource: "crd/ns/b", RecordType: "CNAME"},
+		{Hostname: "b.example.com.", Resource: "crd/other/b", RecordType: "CNAME"},
+		// Of another zone
+		{Hostname: "c.example.org", Resource: "crd/ns/c", RecordType: "A"},
+		// Not a DNSEndpoint
+		{Hostname: "d.example.com", Resource: "ingress/ns/d"},
+	}
+	want := []auditEntry{
+		{Name: "a.example.com.", Type: "AAAA", Resources: []string{"crd/ns/a"}},
+		{Name: "b.example.com.", Type: "CNAME", Resources: []string{"crd/ns/b", "crd/other/b"}},
+	}
+	provider := newMemoryProvider("example.com", records...)
+	domain := zoneDomain(provider, records)
+	if domain != "example.com." {
+		t.Fatalf("zoneDomain() = %s, want example.com.", domain)
+	}
+	if got := missingDNSEndpoints(hostnames, records, domain); !reflect.DeepEqual(got, want) {
+		t.Errorf("missingDNSEndpoints() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Gateway API GVRs (GroupVersionResource)
+var (
+	gatewayGVR = schema.GroupVersionResource{
+		Group:    "gateway.networking.k8s.io",
+		Version:  "v1",
+		Resource: "gateways",
+	}
+	httpRouteGVR = schema.GroupVersionResource{
+		Group:    "gateway.networking.k8s.io",
+		Version:  "v1",
+		Resource: "httproutes",
+	}
+	grpcRouteGVR = schema.GroupVersionResource{
+		Group:    "gateway.networking.k8s.io",
+		Version:  "v1",
+		Resource: "grpcroutes",
+	}
+	tlsRouteGVR = schema.GroupVersionResource{
+		Group:    "gateway.networking.k8s.io",
+		Version:  "v1alpha2",
+		Resource: "tlsroutes",
+	}
+	tcpRouteGVR = schema.GroupVersionResource{
+		Group:    "gateway.networking.k8s.io",
+		Version:  "v1alpha2",
+		Resource: "tcproutes",
+	}
+	udpRouteGVR = schema.GroupVersionResource{
+		Group:    "gateway.networking.k8s.io",
+		Version:  "v1alpha2",
+		Resource: "udproutes",
+	}
+)
+
+// gatewayFallbackVersions are the versions the Gateway API kinds were served
+// at before they graduated to v1, for clusters with older CRDs installed
+var gatewayFallbackVersions = map[schema.GroupVersionResource][]string{
+	gatewayGVR:   {"v1beta1"},
+	httpRouteGVR: {"v1beta1"},
+	grpcRouteGVR: {"v1alpha2"},
+}
+
+// hostnameAnnotation is the external-dns annotation that sets the hostnames
+// of an object, in addition to the ones found in its spec
+const hostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+
+// gatewayHostnames returns the hostnames of the listeners of all the Gateways
+// and the hostnames of all the HTTPRoutes, GRPCRoutes, TLSRoutes, TCPRoutes
+// and UDPRoutes. Routes without hostnames are served on the hostnames of the
+// Gateway listeners. Kinds whose v1 version is not served yet are listed at
+// their older version and kinds that are not installed in the cluster are
+// skipped.
+func gatewayHostnames(client dynamic.Interface) ([]kubeHostname, error) {
+	var hostnames []kubeHostname
+	gateways, err := customResourceList(client, gatewayGVR, gatewayFallbackVersions[gatewayGVR]...)
+	if err != nil {
+		return nil, err
+	}
+	for _, gateway := range gateways {
+		listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+		for _, listener := range listeners {
+			listenerMap, ok := listener.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if hostname, ok := listenerMap["hostname"].(string); ok && hostname != "" {
+				hostnames = append(hostnames, customResourceHostname(gateway, "gateway", hostname))
+			}
+		}
+	}
+
+	routeKinds := []struct {
+		kind string
+		gvr  schema.GroupVersionResource
+	}{
+		{"httproute", httpRouteGVR},
+		{"grpcroute", grpcRouteGVR},
+		{"tlsroute", tlsRouteGVR},
+		{"tcproute", tcpRouteGVR},
+		{"udproute", udpRouteGVR},
+	}
+	for _, r := range routeKinds {
+		routes, err := customResourceList(client, r.gvr, gatewayFallbackVersions[r.gvr]...)
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range routes {
+			hostnames = append(hostnames, gatewayRouteHostnames(route, r.kind)...)
+		}
+	}
+	return hostnames, nil
+}
+
+// gatewayRouteHostnames returns the spec.hostnames of a Gateway API route and
+// the hostnames of its external-dns hostname annotation. TCPRoutes and
+// UDPRoutes only have the latter.
+func gatewayRouteHostnames(route unstructured.Unstructured, kind string) []kubeHostname {
+	var hostnames []kubeHostname
+	hosts, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	if annotation, ok := route.GetAnnotations()[hostnameAnnotation]; ok {
+		for _, host := range strings.Split(annotation, ",") {
+			hosts = append(hosts, strings.TrimSpace(host))
+		}
+	}
+	for _, host := range hosts {
+		if host != "" {
+			hostnames = append(hostnames, customResourceHostname(route, kind, host))
+		}
+	}
+	return hostnames
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// notServed makes the fake dynamic client answer the lists of the group at
+// the passed version as not found, like clusters that do not serve it
+func notServed(client *dynamicfake.FakeDynamicClient, group, version string) {
+	client.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gvr := action.GetResource()
+		if gvr.Group != group || gvr.Version != version {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewNotFound(gvr.GroupResource(), "")
+	})
+}
+
+func gatewayAPIResources(version string) []fakeCustomResource {
+	route := customResource(withVersion(httpRouteGVR, version), "HTTPRoute", "ns", "web", map[string]interface{}{
+		"hostnames": []interface{}{"web.example.com"},
+	})
+	route.obj.SetAnnotations(map[string]string{hostnameAnnotation: "www.example.com, web.example.org"})
+	grpcVersion := version
+	if version == "v1beta1" {
+		grpcVersion = "v1alpha2"
+	}
+	return []fakeCustomResource{
+		customResource(withVersion(gatewayGVR, version), "Gateway", "gateways", "public", map[string]interface{}{
+			"listeners": []interface{}{
+				map[string]interface{}{"name": "https", "hostname": "*.example.com"},
+				map[string]interface{}{"name": "http"},
+			},
+		}),
+		route,
+		// Served on the hostnames of the Gateway listeners
+		customResource(withVersion(httpRouteGVR, version), "HTTPRoute", "ns", "default", map[string]interface{}{}),
+		customResource(withVersion(grpcRouteGVR, grpcVersion), "GRPCRoute", "ns", "api", map[string]interface{}{
+			"hostnames": []interface{}{"api.example.com"},
+		}),
+	}
+}
+
+func TestGatewayHostnames(t *testing.T) {
+	want := []string{
+		"*.example.com gateway/gateways/public",
+		"web.example.com httproute/ns/web",
+		"www.example.com httproute/ns/web",
+		"web.example.org httproute/ns/web",
+		"api.example.com grpcroute/ns/api",
+	}
+	tests := []struct {
+		name      string
+		version   string
+		notServed []string
+	}{
+		{name: "v1", version: "v1"},
+		{name: "pre-1.0 CRDs", version: "v1beta1", notServed: []string{"v1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeDynamicClient(t, gatewayAPIResources(tt.version)...)
+			for _, version := range tt.notServed {
+				notServed(client, gatewayGVR.Group, version)
+			}
+			hostnames, err := gatewayHostnames(client)
+			if err != nil {
+				t.Fatalf("gatewayHostnames() error = %v", err)
+			}
+			if got := hostnameResourcesList(hostnames); !reflect.DeepEqual(got, want) {
+				t.Errorf("gatewayHostnames() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGatewayHostnamesNotInstalled(t *testing.T) {
+	client := newFakeDynamicClient(t)
+	for _, version := range []string{"v1", "v1beta1", "v1alpha2"} {
+		notServed(client, gatewayGVR.Group, version)
+	}
+	hostnames, err := gatewayHostnames(client)
+	if err != nil {
+		t.Fatalf("gatewayHostnames() error = %v", err)
+	}
+	if len(hostnames) != 0 {
+		t.Errorf("gatewayHostnames() = %v, want none", hostnames)
+	}
+}
+
+func TestGatewayRouteHostnames(t *testing.T) {
+	tlsRoute := customResource(tlsRouteGVR, "TLSRoute", "ns", "tls", map[string]interface{}{
+		"hostnames": []interface{}{"tls.example.com"},
+	})
+	tcpRoute := customResource(tcpRouteGVR, "TCPRoute", "ns", "tcp", map[string]interface{}{})
+	tcpRoute.obj.SetAnnotations(map[string]string{hostnameAnnotation: "tcp.example.com"})
+	udpRoute := customResource(udpRouteGVR, "UDPRoute", "ns", "udp", map[string]interface{}{})
+	udpRoute.obj.SetAnnotations(map[string]string{hostnameAnnotation: "udp.example.com,dns.example.com"})
+	client := newFakeDynamicClient(t, tlsRoute, tcpRoute, udpRoute)
+
+	hostnames, err := gatewayHostnames(client)
+	if err != nil {
+		t.Fatalf("gatewayHostnames() error = %v", err)
+	}
+	want := []string{
+		"tls.example.com tlsroute/ns/tls",
+		"tcp.example.com tcproute/ns/tcp",
+		"udp.example.com udproute/ns/udp",
+		"dns.example.com udproute/ns/udp",
+	}
+	if got := hostnameResourcesList(hostnames); !reflect.DeepEqual(got, want) {
+		t.Errorf("gatewayHostnames() = %q, want %q", got, want)
+	}
+}
+
+func TestDeleteOwnerRecordsKeepsPre10GatewayAPIHostnames(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		testRecord("web.example.com", "A", "1.1.1.1"),
+		testRecord("infra-web.example.com", "TXT", registryValue("old", "httproute/ns/web")),
+	)
+	want := zoneRecords(t, provider)
+	client := newFakeDynamicClient(t, gatewayAPIResources("v1beta1")...)
+	notServed(client, gatewayGVR.Group, "v1")
+
+	if err := deleteOwnerRecords(provider, fake.NewClientset(), client, registry, "old", runOptions{backupDir: t.TempDir()}); err != nil {
+		t.Fatalf("deleteOwnerRecords() error = %v", err)
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
@@ -15,7 +15,11 @@ var (
 	externalDNSRegex = regexp.MustCompile(`^external-dns\.alpha\.kubernetes\.io/.*`)
 )
 
-func ingressList(clientset *kubernetes.Clientset) ([]v1.Ingress, error) {
+// ingressClassAnnotation is the deprecated annotation that sets the class of
+// Ingresses and IngressRoutes
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+func ingressList(clientset kubernetes.Interface) ([]v1.Ingress, error) {
 	ingressList, err := clientset.NetworkingV1().Ingresses("").List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list Ingress resources: %w", err)
@@ -24,8 +28,25 @@ func ingressList(clientset *kubernetes.Clientset) ([]v1.Ingress, error) {
 	return ingressList.Items, nil
 }
 
-func allIngressHosts(clientset *kubernetes.Clientset) ([]string, error) {
-	var hostnames []string
+// ingressHostname returns a host of the Ingress rules together with the
+// Ingress details
+func ingressHostname(ingress v1.Ingress, host string) kubeHostname {
+	class := ingress.Annotations[ingressClassAnnotation]
+	if ingress.Spec.IngressClassName != nil {
+		class = *ingress.Spec.IngressClassName
+	}
+	return kubeHostname{
+		Hostname:     host,
+		Resource:     kubeResource("ingress", ingress.Namespace, ingress.Name),
+		Namespace:    ingress.Namespace,
+		Labels:       ingress.Labels,
+		IngressClass: class,
+	}
+}
+
+// ingressHostnames returns the hosts of the rules of all the Ingresses
+func ingressHostnames(clientset kubernetes.Interface) ([]kubeHostname, error) {
+	var hostnames []kubeHostname
 	ingresses, err := ingressList(clientset)
 	if err != nil {
 		return hostnames, err
@@ -33,16 +54,15 @@ func allIngressHosts(clientset *kubernetes.Clientset) ([]string, error) {
 	for _, ingress := range ingresses {
 		for _, rule := range ingress.Spec.Rules {
 			if rule.Host != "" {
-				hostnames = append(hostnames, rule.Host)
+				hostnames = append(hostnames, ingressHostname(ingress, rule.Host))
 			}
 		}
-
 	}
 	return hostnames, nil
 }
 
-func externalDNSIngressHostnames(clientset *kubernetes.Clientset) ([]string, error) {
-	var hostnames []string
+func externalDNSIngressHostnames(clientset kubernetes.Interface) ([]kubeHostname, error) {
+	var hostnames []kubeHostname
 	ingresses, err := ingressList(clientset)
 	if err != nil {
 		return hostnames, err
@@ -52,7 +72,7 @@ func externalDNSIngressHostnames(clientset *kubernetes.Clientset) ([]string, err
 			if externalDNSRegex.MatchString(key) {
 				for _, rule := range ingress.Spec.Rules {
 					if rule.Host != "" {
-						hostnames = append(hostnames, rule.Host)
+						hostnames = append(hostnames, ingressHostname(ingress, rule.Host))
 					}
 				}
 				break // No need to check other annotations for this ingress
@@ -20,7 +20,7 @@ var ingressRouteGVR = schema.GroupVersionResource{
 	Resource: "ingressroutes",
 }
 
-func ingressRouteList(client *dynamic.DynamicClient) ([]runtime.Object, error) {
+func ingressRouteList(client dynamic.Interface) ([]runtime.Object, error) {
 	// Fetch all IngressRoute resources across all namespaces
 	ingressRoutes, err := client.Resource(ingressRouteGVR).Namespace("").List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
@@ -36,6 +36,36 @@ func ingressRouteList(client *dynamic.DynamicClient) ([]runtime.Object, error) {
 	return results, nil
 }
 
+// ingressRouteHostnames returns the hostnames of the rules of all the
+// IngressRoutes
+func ingressRouteHostnames(client dynamic.Interface) ([]kubeHostname, error) {
+	var hostnames []kubeHostname
+	ingressRoutes, err := ingressRouteList(client)
+	if err != nil {
+		return hostnames, err
+	}
+	for _, obj := range ingressRoutes {
+		hosts, err := extractHostnamesFromIngressRoutes([]runtime.Object{obj})
+		if err != nil {
+			return nil, err
+		}
+		u := obj.(*unstructured.Unstructured)
+		for _, host := range hosts {
+			if host == "" {
+				continue
+			}
+			hostnames = append(hostnames, kubeHostname{
+				Hostname:     host,
+				Resource:     kubeResource("ingressroute", u.GetNamespace(), u.GetName()),
+				Namespace:    u.GetNamespace(),
+				Labels:       u.GetLabels(),
+				IngressClass: u.GetAnnotations()[ingressClassAnnotation],
+			})
+		}
+	}
+	return hostnames, nil
+}
+
 // extractHostnamesFromIngressRoutes parses the list of IngressRoutes and extracts all hostnames.
 func extractHostnamesFromIngressRoutes(ingressRoutes []runtime.Object) ([]string, error) {
 	var hostnames []string
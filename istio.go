@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Istio GVRs (GroupVersionResource)
+var (
+	istioGatewayGVR = schema.GroupVersionResource{
+		Group:    "networking.istio.io",
+		Version:  "v1beta1",
+		Resource: "gateways",
+	}
+	istioVirtualServiceGVR = schema.GroupVersionResource{
+		Group:    "networking.istio.io",
+		Version:  "v1beta1",
+		Resource: "virtualservices",
+	}
+)
+
+// istioHostnames returns the hosts of the servers of all the Istio Gateways
+// and the hosts of all the VirtualServices. Istio resources are skipped in
+// clusters where they are not installed.
+func istioHostnames(client dynamic.Interface) ([]kubeHostname, error) {
+	var hostnames []kubeHostname
+	gateways, err := customResourceList(client, istioGatewayGVR)
+	if err != nil {
+		return nil, err
+	}
+	for _, gateway := range gateways {
+		servers, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "servers")
+		for _, server := range servers {
+			serverMap, ok := server.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			hosts, _, _ := unstructured.NestedStringSlice(serverMap, "hosts")
+			for _, host := range hosts {
+				if host = istioHost(host); host != "" {
+					hostnames = append(hostnames, customResourceHostname(gateway, "gateway", host))
+				}
+			}
+		}
+	}
+
+	virtualServices, err := customResourceList(client, istioVirtualServiceGVR)
+	if err != nil {
+		return nil, err
+	}
+	for _, virtualService := range virtualServices {
+		hosts, _, _ := unstructured.NestedStringSlice(virtualService.Object, "spec", "hosts")
+		for _, host := range hosts {
+			if host = istioHost(host); host != "" {
+				hostnames = append(hostnames, customResourceHostname(virtualService, "virtualservice", host))
+			}
+		}
+	}
+	return hostnames, nil
+}
+
+// istioHost strips the namespace of an Istio host in the form of
+// <namespace>/<host>, where the namespace can also be . or *. Hosts that match
+// everything are skipped.
+func istioHost(host string) string {
+	if _, h, ok := strings.Cut(host, "/"); ok {
+		host = h
+	}
+	if host == "*" {
+		return ""
+	}
+	return host
+}
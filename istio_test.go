@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIstioHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "a.example.com", want: "a.example.com"},
+		{host: "ns/a.example.com", want: "a.example.com"},
+		{host: "./a.example.com", want: "a.example.com"},
+		{host: "*/a.example.com", want: "a.example.com"},
+		{host: "*.example.com", want: "*.example.com"},
+		{host: "*", want: ""},
+		{host: "ns/*", want: ""},
+	}
+	for _, tt := range tests {
+		if got := istioHost(tt.host); got != tt.want {
+			t.Errorf("istioHost(%s) = %s, want %s", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestIstioHostnames(t *testing.T) {
+	client := newFakeDynamicClient(t,
+		customResource(istioGatewayGVR, "Gateway", "istio-system", "public", map[string]interface{}{
+			"servers": []interface{}{
+				map[string]interface{}{"hosts": []interface{}{"ns/a.example.com", "*"}},
+				map[string]interface{}{"hosts": []interface{}{"b.example.com"}},
+			},
+		}),
+		customResource(istioVirtualServiceGVR, "VirtualService", "ns", "web", map[string]interface{}{
+			"hosts": []interface{}{"web.example.com", "web.ns.svc.cluster.local"},
+		}),
+	)
+	hostnames, err := istioHostnames(client)
+	if err != nil {
+		t.Fatalf("istioHostnames() error = %v", err)
+	}
+	want := []string{
+		"a.example.com gateway/istio-system/public",
+		"b.example.com gateway/istio-system/public",
+		"web.example.com virtualservice/ns/web",
+		"web.ns.svc.cluster.local virtualservice/ns/web",
+	}
+	if got := hostnameResourcesList(hostnames); !reflect.DeepEqual(got, want) {
+		t.Errorf("istioHostnames() = %q, want %q", got, want)
+	}
+}
+
+func TestIstioHostnamesNotInstalled(t *testing.T) {
+	client := newFakeDynamicClient(t)
+	notServed(client, istioGatewayGVR.Group, istioGatewayGVR.Version)
+	hostnames, err := istioHostnames(client)
+	if err != nil {
+		t.Fatalf("istioHostnames() error = %v", err)
+	}
+	if len(hostnames) != 0 {
+		t.Errorf("istioHostnames() = %v, want none", hostnames)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// journalEntry is a change applied to a zone, as recorded in a journal file
+type journalEntry struct {
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider"`
+	Zone     string    `json:"zone"`
+	change
+}
+
+// journal appends the changes applied to a zone to a file, one JSON entry per
+// line, as they happen.
+type journal struct {
+	provider DNSProvider
+	file     *os.File
+}
+
+// openJournal opens the journal file at path for appending, or creates a new
+// journal file in dir if path is empty.
+func openJournal(path, dir string, provider DNSProvider) (*journal, error) {
+	var f *os.File
+	var err error
+	if path != "" {
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			err = fmt.Errorf("failed to open journal: %w", err)
+		}
+	} else {
+		f, err = createBackupFile(dir, provider, time.Now().UTC(), "journal.jsonl")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &journal{provider: provider, file: f}, nil
+}
+
+func (j *journal) path() string {
+	return j.file.Name()
+}
+
+func (j *journal) record(c change) error {
+	entry := journalEntry{
+		Time:     time.Now().UTC(),
+		Provider: j.provider.Name(),
+		Zone:     j.provider.Zone(),
+		change:   c,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+func readJournal(path string) ([]journalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse journal %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return entries, nil
+}
+
+// rollbackJournal reverts the changes recorded in a journal file in reverse
+// order. It refuses to run if any of the records no longer match the state
+// they were left in by the recorded changes.
+func rollbackJournal(provider DNSProvider, path string, opts runOptions) error {
+	entries, err := readJournal(path)
+	if err != nil {
+		return err
+	}
+	var changes []change
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Provider != provider.Name() || entry.Zone != provider.Zone() {
+			return fmt.Errorf("journal entry %d is for %s zone: %s, not %s zone: %s", i+1, entry.Provider, entry.Zone, provider.Name(), provider.Zone())
+		}
+		c, err := invertChange(entry.change)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, c)
+	}
+
+	records, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+	perValue := recordPerValue(provider)
+	if err := verifyChanges(changes, records, perValue); err != nil {
+		return fmt.Errorf("Refusing to roll back journal %s: %v", path, err)
+	}
+	// Records that were created by the journal changes have no ID recorded,
+	// use the ones found in the zone instead
+	for i, c := range changes {
+		if c.Before == nil || c.Before.ID != "" {
+			continue
+		}
+		if j := matchRecord(records, *c.Before, perValue); j >= 0 {
+			live := copyRecord(records[j])
+			live.Values = c.Before.Values
+			changes[i].Before = &live
+		}
+	}
+	return executeChanges(provider, records, changes, opts)
+}
+
+// invertChange returns the change that reverts the passed one
+func invertChange(c change) (change, error) {
+	switch c.Action {
+	case actionUpdate:
+		return updateChange(c.Hostname, *c.After, c.Before.Values), nil
+	case actionDelete:
+		record := *c.Before
+		record.ID = ""
+		return createChange(c.Hostname, record), nil
+	case actionCreate:
+		return deleteChange(c.Hostname, *c.After), nil
+	}
+	return change{}, fmt.Errorf("unknown action: %s", c.Action)
+}
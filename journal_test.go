@@ -0,0 +1,163 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestInvertChange(t *testing.T) {
+	a := withID(testRecord("a.example.com", "A", "1.1.1.1"), "a")
+	tests := []struct {
+		name   string
+		change change
+		want   []string
+	}{
+		{
+			name:   "update",
+			change: updateChange("a.example.com", a, []string{"2.2.2.2"}),
+			want:   []string{"update a.example.com. #a A 1.1.1.1"},
+		},
+		{
+			name:   "delete",
+			change: deleteChange("a.example.com", a),
+			want:   []string{"create a.example.com. A 1.1.1.1"},
+		},
+		{
+			name:   "create",
+			change: createChange("a.example.com", a),
+			want:   []string{"delete a.example.com. #a A 1.1.1.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := invertChange(tt.change)
+			if err != nil {
+				t.Fatalf("invertChange() error = %v", err)
+			}
+			if got := changeList([]change{c}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("invertChange() = %q, want %q", got, tt.want)
+			}
+			if c.Action == actionCreate && c.After.ID != "" {
+				t.Errorf("invertChange() created record has ID: %s", c.After.ID)
+			}
+		})
+	}
+	if _, err := invertChange(change{Action: "replace"}); err == nil {
+		t.Error("invertChange() error = nil for an unknown action")
+	}
+}
+
+func TestVerifyChanges(t *testing.T) {
+	a := testRecord("a.example.com", "A", "1.1.1.1")
+	// Cloudflare lists a record for every value
+	cloudflareRecords := []Record{
+		withID(testRecord("a.example.com", "A", "1.1.1.1"), "a"),
+		withID(testRecord("a.example.com", "A", "2.2.2.2"), "b"),
+	}
+	tests := []struct {
+		name     string
+		changes  []change
+		records  []Record
+		perValue bool
+		wantErr  bool
+	}{
+		{
+			name:    "records match",
+			changes: []change{updateChange("a.example.com", a, []string{"2.2.2.2"})},
+			records: []Record{a},
+		},
+		{
+			name:    "record values changed",
+			changes: []change{updateChange("a.example.com", a, []string{"2.2.2.2"})},
+			records: []Record{testRecord("a.example.com", "A", "3.3.3.3")},
+			wantErr: true,
+		},
+		{
+			name:    "record no longer exists",
+			changes: []change{deleteChange("a.example.com", a)},
+			wantErr: true,
+		},
+		{
+			name:    "record to create already exists",
+			changes: []change{createChange("a.example.com", a)},
+			records: []Record{testRecord("a.example.com", "A", "3.3.3.3")},
+			wantErr: true,
+		},
+		{
+			name: "changes are verified in order",
+			changes: []change{
+				deleteChange("a.example.com", a),
+				createChange("a.example.com", testRecord("a.example.com", "A", "2.2.2.2")),
+			},
+			records: []Record{a},
+		},
+		{
+			name: "records of every value are created",
+			changes: []change{
+				createChange("a.example.com", testRecord("a.example.com", "A", "1.1.1.1")),
+				createChange("a.example.com", testRecord("a.example.com", "A", "2.2.2.2")),
+			},
+			perValue: true,
+		},
+		{
+			name:     "record of a value to create already exists",
+			changes:  []change{createChange("a.example.com", testRecord("a.example.com", "A", "2.2.2.2"))},
+			records:  cloudflareRecords,
+			perValue: true,
+			wantErr:  true,
+		},
+		{
+			name:     "records of a value are matched by ID",
+			changes:  []change{deleteChange("a.example.com", cloudflareRecords[1])},
+			records:  cloudflareRecords,
+			perValue: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChanges(tt.changes, tt.records, tt.perValue)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyChanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRollbackJournal(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		testRecord("c.example.com", "A", "3.3.3.3"),
+		testRecord("infra-c.example.com", "TXT", registryValue("old", "ingress/ns/c")),
+	)
+	want := zoneRecords(t, provider)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.jsonl")
+
+	hostnames := []kubeHostname{{Hostname: "a.example.com"}}
+	migration := ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"}
+	if err := migrateOwner(provider, hostnames, registry, migration, runOptions{backupDir: dir, journalPath: path}); err != nil {
+		t.Fatalf("migrateOwner() error = %v", err)
+	}
+	if err := deleteOwnerRecords(provider, fake.NewClientset(), newFakeDynamicClient(t), registry, "old", runOptions{backupDir: dir, journalPath: path}); err != nil {
+		t.Fatalf("deleteOwnerRecords() error = %v", err)
+	}
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatalf("readJournal() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("readJournal() = %d entries, want 3", len(entries))
+	}
+
+	if err := rollbackJournal(provider, path, runOptions{backupDir: dir}); err != nil {
+		t.Fatalf("rollbackJournal() error = %v", err)
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
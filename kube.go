@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -42,16 +49,171 @@ func getClientConfig(path, context string) (*rest.Config, error) {
 	return rest.InClusterConfig()
 }
 
+// kubeHostname is a hostname found in a cluster together with the object that
+// declares it
+type kubeHostname struct {
+	Hostname string
+	// Resource is the object in the form of the external-dns/resource label:
+	// <kind>/<namespace>/<name>
+	Resource     string
+	Namespace    string
+	Labels       map[string]string
+	IngressClass string
+	// RecordType is the type of the record of the hostname, for objects
+	// that declare it (DNSEndpoints)
+	RecordType string
+}
+
 // externalDNSKubeHostnames will return all the hostnames found in a cluster
 // that shall be managed by externalDNS
-func externalDNSKubeHostnames(kubeClient *kubernetes.Clientset) ([]string, error) {
+func externalDNSKubeHostnames(kubeClient kubernetes.Interface, dynamicKubeClient dynamic.Interface) ([]kubeHostname, error) {
 	ingresses, err := externalDNSIngressHostnames(kubeClient)
 	if err != nil {
-		return []string{}, fmt.Errorf("Cannot list Ingresses: %v", err)
+		return []kubeHostname{}, fmt.Errorf("Cannot list Ingresses: %v", err)
 	}
 	services, err := externalDNSServiceHostnames(kubeClient)
 	if err != nil {
-		return []string{}, fmt.Errorf("Cannot list Services: %v", err)
+		return []kubeHostname{}, fmt.Errorf("Cannot list Services: %v", err)
+	}
+	customResources, err := customResourceHostnames(dynamicKubeClient)
+	if err != nil {
+		return []kubeHostname{}, err
+	}
+	hostnames := append(ingresses, services...)
+	return append(hostnames, customResources...), nil
+}
+
+// clusterHostnames will return all the hostnames found in a cluster, whether
+// they are annotated for externalDNS or not. Records of these hostnames are
+// still in use.
+func clusterHostnames(kubeClient kubernetes.Interface, dynamicKubeClient dynamic.Interface) ([]kubeHostname, error) {
+	ingresses, err := ingressHostnames(kubeClient)
+	if err != nil {
+		return []kubeHostname{}, fmt.Errorf("Cannot list Ingresses: %v", err)
+	}
+	ingressRoutes, err := ingressRouteHostnames(dynamicKubeClient)
+	if err != nil {
+		return []kubeHostname{}, fmt.Errorf("Cannot list IngressRoutes: %v", err)
+	}
+	services, err := externalDNSServiceHostnames(kubeClient)
+	if err != nil {
+		return []kubeHostname{}, fmt.Errorf("Cannot list Services: %v", err)
+	}
+	customResources, err := customResourceHostnames(dynamicKubeClient)
+	if err != nil {
+		return []kubeHostname{}, err
+	}
+	hostnames := append(ingresses, ingressRoutes...)
+	hostnames = append(hostnames, services...)
+	return append(hostnames, customResources...), nil
+}
+
+// customResourceHostnames will return the hostnames of all the custom
+// resources that externalDNS sources hostnames from
+func customResourceHostnames(dynamicKubeClient dynamic.Interface) ([]kubeHostname, error) {
+	gateways, err := gatewayHostnames(dynamicKubeClient)
+	if err != nil {
+		return []kubeHostname{}, fmt.Errorf("Cannot list Gateway API resources: %v", err)
+	}
+	istio, err := istioHostnames(dynamicKubeClient)
+	if err != nil {
+		return []kubeHostname{}, fmt.Errorf("Cannot list Istio resources: %v", err)
+	}
+	dnsEndpoints, err := dnsEndpointHostnames(dynamicKubeClient)
+	if err != nil {
+		return []kubeHostname{}, fmt.Errorf("Cannot list DNSEndpoints: %v", err)
+	}
+	httpProxies, err := httpProxyHostnames(dynamicKubeClient)
+	if err != nil {
+		return []kubeHostname{}, fmt.Errorf("Cannot list HTTPProxies: %v", err)
+	}
+	hostnames := append(gateways, istio...)
+	hostnames = append(hostnames, dnsEndpoints...)
+	return append(hostnames, httpProxies...), nil
+}
+
+// customResourceList lists the objects of a custom resource in all the
+// namespaces. When the version of the GVR is not served the fallback versions
+// are tried in order. Clusters where the custom resource is not installed at
+// any of the versions have none.
+func customResourceList(client dynamic.Interface, gvr schema.GroupVersionResource, fallbackVersions ...string) ([]unstructured.Unstructured, error) {
+	for _, version := range append([]string{gvr.Version}, fallbackVersions...) {
+		gvr.Version = version
+		list, err := client.Resource(gvr).Namespace("").List(context.TODO(), metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s.%s/%s resources: %w", gvr.Resource, gvr.Group, gvr.Version, err)
+		}
+		return list.Items, nil
+	}
+	fmt.Printf("Skipping %s.%s: not installed in the cluster\n", gvr.Resource, gvr.Group)
+	return nil, nil
+}
+
+// customResourceHostname returns a hostname found in a custom resource
+// together with the resource details
+func customResourceHostname(obj unstructured.Unstructured, kind, hostname string) kubeHostname {
+	return kubeHostname{
+		Hostname:  hostname,
+		Resource:  kubeResource(kind, obj.GetNamespace(), obj.GetName()),
+		Namespace: obj.GetNamespace(),
+		Labels:    obj.GetLabels(),
+	}
+}
+
+// kubeResource returns the external-dns/resource label value of an object
+func kubeResource(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// hostnamesList returns the hostnames of a list of kubeHostnames
+func hostnamesList(hostnames []kubeHostname) []string {
+	list := make([]string, len(hostnames))
+	for i, h := range hostnames {
+		list[i] = h.Hostname
+	}
+	return list
+}
+
+// hostnameObjects maps every hostname to all the objects that declare it
+func hostnameObjects(hostnames []kubeHostname) map[string][]string {
+	objects := map[string][]string{}
+	for _, h := range hostnames {
+		name := sanitizeDNSAddress(h.Hostname)
+		if !slices.Contains(objects[name], h.Resource) {
+			objects[name] = append(objects[name], h.Resource)
+		}
+	}
+	return objects
+}
+
+// hostnameResources maps every hostname to the object that declares it.
+// Hostnames declared by more than one object map to an empty resource, as the
+// owning object is ambiguous.
+func hostnameResources(hostnames []kubeHostname) map[string]string {
+	resources := map[string]string{}
+	for _, h := range hostnames {
+		name := sanitizeDNSAddress(h.Hostname)
+		if r, ok := resources[name]; ok && r != h.Resource {
+			resources[name] = ""
+			continue
+		}
+		resources[name] = h.Resource
+	}
+	return resources
+}
+
+// hostnameRecordTypes maps every hostname to the record types declared for it
+// by the objects that declare them (DNSEndpoints)
+func hostnameRecordTypes(hostnames []kubeHostname) map[string][]string {
+	recordTypes := map[string][]string{}
+	for _, h := range hostnames {
+		name := sanitizeDNSAddress(h.Hostname)
+		if h.RecordType != "" && !slices.Contains(recordTypes[name], h.RecordType) {
+			recordTypes[name] = append(recordTypes[name], h.RecordType)
+		}
 	}
-	return append(ingresses, services...), nil
+	return recordTypes
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeCustomResource is an object served by the fake dynamic client
+type fakeCustomResource struct {
+	gvr schema.GroupVersionResource
+	obj *unstructured.Unstructured
+}
+
+// customResource returns a namespaced custom resource of the GVR with the
+// passed spec. Nested lists of the spec must be []interface{}.
+func customResource(gvr schema.GroupVersionResource, kind, namespace, name string, spec map[string]interface{}) fakeCustomResource {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetAPIVersion(gvr.GroupVersion().String())
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return fakeCustomResource{gvr: gvr, obj: obj}
+}
+
+// withVersion returns the GVR at another version
+func withVersion(gvr schema.GroupVersionResource, version string) schema.GroupVersionResource {
+	gvr.Version = version
+	return gvr
+}
+
+// newFakeDynamicClient returns a fake dynamic client that serves all the
+// custom resources the hostnames are discovered in, seeded with the passed
+// objects. Objects are created through the client, as the fake client cannot
+// guess the resource of kinds like Gateway from their name.
+func newFakeDynamicClient(t *testing.T, resources ...fakeCustomResource) *dynamicfake.FakeDynamicClient {
+	t.Helper()
+	listKinds := map[schema.GroupVersionResource]string{
+		ingressRouteGVR:                       "IngressRouteList",
+		gatewayGVR:                            "GatewayList",
+		withVersion(gatewayGVR, "v1beta1"):    "GatewayList",
+		httpRouteGVR:                          "HTTPRouteList",
+		withVersion(httpRouteGVR, "v1beta1"):  "HTTPRouteList",
+		grpcRouteGVR:                          "GRPCRouteList",
+		withVersion(grpcRouteGVR, "v1alpha2"): "GRPCRouteList",
+		tlsRouteGVR:                           "TLSRouteList",
+		tcpRouteGVR:                           "TCPRouteList",
+		udpRouteGVR:                           "UDPRouteList",
+		istioGatewayGVR:                       "GatewayList",
+		istioVirtualServiceGVR:                "VirtualServiceList",
+		dnsEndpointGVR:                        "DNSEndpointList",
+		httpProxyGVR:                          "HTTPProxyList",
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	for _, r := range resources {
+		if _, err := client.Resource(r.gvr).Namespace(r.obj.GetNamespace()).Create(context.TODO(), r.obj, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("failed to create %s %s/%s: %v", r.gvr.Resource, r.obj.GetNamespace(), r.obj.GetName(), err)
+		}
+	}
+	return client
+}
+
+// ingress returns an Ingress with a rule for every host
+func ingress(namespace, name string, annotations map[string]string, hosts ...string) *networkingv1.Ingress {
+	i := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+	}
+	for _, host := range hosts {
+		i.Spec.Rules = append(i.Spec.Rules, networkingv1.IngressRule{Host: host})
+	}
+	return i
+}
+
+// hostnameResourcesList returns the hostnames in the form of
+// <hostname> <resource>, for comparison
+func hostnameResourcesList(hostnames []kubeHostname) []string {
+	var list []string
+	for _, h := range hostnames {
+		list = append(list, h.Hostname+" "+h.Resource)
+	}
+	return list
+}
+
+func TestHostnameObjects(t *testing.T) {
+	hostnames := []kubeHostname{
+		{Hostname: "a.example.com", Resource: "ingress/ns/a"},
+		{Hostname: "a.example.com.", Resource: "service/ns/a"},
+		{Hostname: "a.example.com", Resource: "ingress/ns/a"},
+		{Hostname: "b.example.com", Resource: "ingress/ns/b"},
+	}
+	want := map[string][]string{
+		"a.example.com.": {"ingress/ns/a", "service/ns/a"},
+		"b.example.com.": {"ingress/ns/b"},
+	}
+	if got := hostnameObjects(hostnames); !reflect.DeepEqual(got, want) {
+		t.Errorf("hostnameObjects() = %v, want %v", got, want)
+	}
+}
+
+func TestHostnameResources(t *testing.T) {
+	hostnames := []kubeHostname{
+		{Hostname: "a.example.com", Resource: "ingress/ns/a"},
+		{Hostname: "a.example.com", Resource: "ingress/ns/a"},
+		{Hostname: "b.example.com", Resource: "ingress/ns/b"},
+		{Hostname: "b.example.com.", Resource: "service/ns/b"},
+	}
+	want := map[string]string{
+		"a.example.com.": "ingress/ns/a",
+		"b.example.com.": "",
+	}
+	if got := hostnameResources(hostnames); !reflect.DeepEqual(got, want) {
+		t.Errorf("hostnameResources() = %v, want %v", got, want)
+	}
+}
+
+func TestExternalDNSKubeHostnames(t *testing.T) {
+	kubeClient := fake.NewClientset(
+		ingress("ns", "a", map[string]string{"external-dns.alpha.kubernetes.io/target": "lb.example.net"}, "a.example.com", "b.example.com"),
+		ingress("ns", "c", nil, "c.example.com"),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        "d",
+			Annotations: map[string]string{"external-dns.alpha.kubernetes.io/hostname": "d.example.com"},
+		}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "e"}},
+	)
+	hostnames, err := externalDNSKubeHostnames(kubeClient, newFakeDynamicClient(t))
+	if err != nil {
+		t.Fatalf("externalDNSKubeHostnames() error = %v", err)
+	}
+	want := []string{
+		"a.example.com ingress/ns/a",
+		"b.example.com ingress/ns/a",
+		"d.example.com service/ns/d",
+	}
+	if got := hostnameResourcesList(hostnames); !reflect.DeepEqual(got, want) {
+		t.Errorf("externalDNSKubeHostnames() = %q, want %q", got, want)
+	}
+}
+
+func TestClusterHostnames(t *testing.T) {
+	kubeClient := fake.NewClientset(
+		ingress("ns", "a", nil, "a.example.com"),
+	)
+	dynamicKubeClient := newFakeDynamicClient(t,
+		customResource(ingressRouteGVR, "IngressRoute", "ns", "b", map[string]interface{}{
+			"routes": []interface{}{
+				map[string]interface{}{"match": "Host(`b.example.com`) && PathPrefix(`/api`)"},
+			},
+		}),
+	)
+	hostnames, err := clusterHostnames(kubeClient, dynamicKubeClient)
+	if err != nil {
+		t.Fatalf("clusterHostnames() error = %v", err)
+	}
+	want := []string{
+		"a.example.com ingress/ns/a",
+		"b.example.com ingressroute/ns/b",
+	}
+	if got := hostnameResourcesList(hostnames); !reflect.DeepEqual(got, want) {
+		t.Errorf("clusterHostnames() = %q, want %q", got, want)
+	}
+}
+
+func TestHostnameRecordTypes(t *testing.T) {
+	hostnames := []kubeHostname{
+		{Hostname: "a.example.com", Resource: "crd/ns/a", RecordType: "A"},
+		{Hostname: "a.example.com.", Resource: "crd/ns/a", RecordType: "AAAA"},
+		{Hostname: "a.example.com", Resource: "crd/other/a", RecordType: "A"},
+		{Hostname: "b.example.com", Resource: "ingress/ns/b"},
+	}
+	want := map[string][]string{
+		"a.example.com.": {"A", "AAAA"},
+	}
+	if got := hostnameRecordTypes(hostnames); !reflect.DeepEqual(got, want) {
+		t.Errorf("hostnameRecordTypes() = %v, want %v", got, want)
+	}
+}
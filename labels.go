@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	heritage      = "external-dns"
+	labelOwner    = "owner"
+	labelResource = "resource"
+	// txtStringLength is the maximum length of a single TXT character string
+	txtStringLength = 255
+)
+
+// labels are the key value pairs external-dns keeps in its TXT registry
+// records, keyed without the external-dns/ prefix.
+type labels map[string]string
+
+// parseLabels expects a plain text TXT record value in the form of:
+// heritage=external-dns,external-dns/owner=infra,external-dns/resource=ingress/sys-terraform-applier/terraform-applier-pub
+// and returns its labels. An error is returned if the text does not carry the
+// external-dns heritage.
+func parseLabels(text string) (labels, error) {
+	l := labels{}
+	foundHeritage := false
+	for _, token := range strings.Split(text, ",") {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok {
+			continue
+		}
+		if key == "heritage" {
+			if value != heritage {
+				return nil, fmt.Errorf("invalid heritage: %s", value)
+			}
+			foundHeritage = true
+			continue
+		}
+		if name, ok := strings.CutPrefix(key, heritage+"/"); ok {
+			l[name] = value
+		}
+	}
+	if !foundHeritage {
+		return nil, fmt.Errorf("`heritage=%s` not found in input", heritage)
+	}
+	return l, nil
+}
+
+// String serializes the labels like external-dns does, with the heritage
+// first and the labels sorted by key.
+func (l labels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tokens := []string{"heritage=" + heritage}
+	for _, key := range keys {
+		tokens = append(tokens, fmt.Sprintf("%s/%s=%s", heritage, key, l[key]))
+	}
+	return strings.Join(tokens, ",")
+}
+
+// equal returns true if both label sets hold the same key value pairs
+func (l labels) equal(other labels) bool {
+	if len(l) != len(other) {
+		return false
+	}
+	for key, value := range l {
+		if v, ok := other[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (l labels) copy() labels {
+	c := make(labels, len(l))
+	for key, value := range l {
+		c[key] = value
+	}
+	return c
+}
+
+// txtFormat describes how a TXT registry value is stored in a zone. Route53
+// and Cloud DNS return TXT values quoted while Cloudflare does not, and
+// external-dns may encrypt the registry text.
+type txtFormat struct {
+	quoted    bool
+	encrypted bool
+}
+
+// unquoteTXT returns the text of a TXT record value and whether it was quoted.
+// The character strings of quoted multi-string values (`"part1" "part2"`) are
+// joined together.
+func unquoteTXT(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, `"`) {
+		return value, false
+	}
+	var b strings.Builder
+	inQuotes, escaped := false, false
+	for _, c := range value {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case inQuotes && c == '\\':
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+			b.WriteRune(c)
+		}
+	}
+	return b.String(), true
+}
+
+// txtEscaper escapes the characters that have a special meaning in quoted TXT
+// character strings
+var txtEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// quoteTXT quotes the text as a TXT record value, splitting it into multiple
+// character strings if it is too long for a single one
+func quoteTXT(text string) string {
+	var parts []string
+	for len(text) > txtStringLength {
+		parts = append(parts, `"`+txtEscaper.Replace(text[:txtStringLength])+`"`)
+		text = text[txtStringLength:]
+	}
+	parts = append(parts, `"`+txtEscaper.Replace(text)+`"`)
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    labels
+		wantErr bool
+	}{
+		{
+			name: "owner and resource",
+			text: "heritage=external-dns,external-dns/owner=infra,external-dns/resource=ingress/ns/a",
+			want: labels{labelOwner: "infra", labelResource: "ingress/ns/a"},
+		},
+		{
+			name: "labels in any order",
+			text: "external-dns/resource=ingress/ns/a,heritage=external-dns,external-dns/owner=infra",
+			want: labels{labelOwner: "infra", labelResource: "ingress/ns/a"},
+		},
+		{
+			name: "values containing equal signs",
+			text: "heritage=external-dns,external-dns/owner=infra,external-dns/aws-weight=a=b",
+			want: labels{labelOwner: "infra", "aws-weight": "a=b"},
+		},
+		{
+			name: "tokens that are not labels are ignored",
+			text: "heritage=external-dns,external-dns/owner=infra,foo=bar,baz",
+			want: labels{labelOwner: "infra"},
+		},
+		{
+			name:    "no heritage",
+			text:    "external-dns/owner=infra",
+			wantErr: true,
+		},
+		{
+			name:    "other heritage",
+			text:    "heritage=other,external-dns/owner=infra",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLabels(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLabels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelsString(t *testing.T) {
+	l := labels{labelResource: "ingress/ns/a", labelOwner: "infra", "aws-weight": "10"}
+	want := "heritage=external-dns,external-dns/aws-weight=10,external-dns/owner=infra,external-dns/resource=ingress/ns/a"
+	if got := l.String(); got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
+
+func TestQuoteTXT(t *testing.T) {
+	long := strings.Repeat("a", txtStringLength) + "b"
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "heritage=external-dns", want: `"heritage=external-dns"`},
+		{name: "quotes and backslashes", text: `a"b\c`, want: `"a\"b\\c"`},
+		{name: "longer than a character string", text: long, want: `"` + long[:txtStringLength] + `" "b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quoteTXT(tt.text)
+			if got != tt.want {
+				t.Errorf("quoteTXT() = %s, want %s", got, tt.want)
+			}
+			text, quoted := unquoteTXT(got)
+			if !quoted || text != tt.text {
+				t.Errorf("unquoteTXT() = %s, %v, want %s, true", text, quoted, tt.text)
+			}
+		})
+	}
+}
+
+func TestUnquoteTXT(t *testing.T) {
+	tests := []struct {
+		value      string
+		want       string
+		wantQuoted bool
+	}{
+		{value: "heritage=external-dns", want: "heritage=external-dns"},
+		{value: `"heritage=external-dns"`, want: "heritage=external-dns", wantQuoted: true},
+		{value: ` "heritage=" "external-dns" `, want: "heritage=external-dns", wantQuoted: true},
+	}
+	for _, tt := range tests {
+		text, quoted := unquoteTXT(tt.value)
+		if text != tt.want || quoted != tt.wantQuoted {
+			t.Errorf("unquoteTXT(%s) = %s, %v, want %s, %v", tt.value, text, quoted, tt.want, tt.wantQuoted)
+		}
+	}
+}
+
+func TestRewriteLabelsKeepsFormat(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "quoted",
+			value: registryValue("old", "ingress/ns/a"),
+			want:  registryValue("new", "ingress/ns/a"),
+		},
+		{
+			name:  "unquoted",
+			value: "heritage=external-dns,external-dns/owner=old,external-dns/resource=ingress/ns/a",
+			want:  "heritage=external-dns,external-dns/owner=new,external-dns/resource=ingress/ns/a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := registry.rewriteLabels(tt.value, func(l labels) { l[labelOwner] = "new" })
+			if err != nil || !changed || got != tt.want {
+				t.Errorf("rewriteLabels() = %s, %v, %v, want %s, true, nil", got, changed, err, tt.want)
+			}
+		})
+	}
+	if _, changed, _ := registry.rewriteLabels(registryValue("new", ""), func(l labels) { l[labelOwner] = "new" }); changed {
+		t.Error("rewriteLabels() changed = true for unchanged labels")
+	}
+}
@@ -8,19 +8,47 @@ import (
 )
 
 var (
-	flagAWSZoneID             = flag.String("aws-zone-id", getEnv("MIGRATOR_AWS_ZONE_ID", ""), "AWS Route53 Zone ID")
-	flagCloudflareZoneName    = flag.String("cloudflare-zone-name", getEnv("MIGRATOR_CF_ZONE_NAME", ""), "Cloudflare DNS zone name")
-	flagDelete                = flag.Bool("delete", false, "Delete function will look for DNS records of an old owner and delete them. Not implemented yet")
-	flagDryRun                = flag.Bool("dry-run", true, "Whether to dry run or actually apply changes. Defaults to true")
-	flagExternalDNSOwnerIDNew = flag.String("external-dns-owner-id-new", getEnv("MIGRATOR_EXTERNAL_DNS_OWNER_ID_NEW", ""), "New ExternalDNS owner ID. Required for migration")
-	flagExternalDNSOwnerIDOld = flag.String("external-dns-owner-id-old", getEnv("MIGRATOR_EXTERNAL_DNS_OWNER_ID_OLD", ""), "ExternalDNS owner ID to be replaced. Required for migration and deletion")
-	flagExternalDNSPrefix     = flag.String("external-dns-prefix", getEnv("MIGRATOR_EXTERNAL_DNS_PREFIX", ""), "Prefix of ExternalDNS TXT records. Required for migration and deletion")
-	flagGCPZoneName           = flag.String("gcp-zone-name", getEnv("MIGRATOR_GCP_ZONE_NAME", ""), "GCP DNS zone name")
-	flagGCPProjectID          = flag.String("gcp-project-id", getEnv("MIGRATOR_GCP_PROJECT_ID", ""), "GCP project id")
-	flagMigrate               = flag.Bool("migrate", false, "Migrate function will migrate owners to the a new ID")
-	flagProvider              = flag.String("provider", getEnv("MIGRATOR_PROVIDER", ""), "(required) The cloud provider of the DNS zones to manage records. [aws|cloudflare|gcp]")
-	flagKubeContext           = flag.String("kube-context", getEnv("MIGRATOR_KUBE_CONTEXT", ""), "Kubernetes cluster context to look for extarnal-DNS ingresses")
-	flagKubeConfigPath        = flag.String("kube-config", getEnv("MIGRATOR_KUBE_CONFIG", ""), "Path to the local kube config. If not set ~/.kube/config will be used")
+	flagAdopt                      = flag.Bool("adopt", false, "Adopt function will create TXT records owned by the new owner ID for the records of the hostnames found in the cluster that have no external-DNS TXT records")
+	flagApply                      = flag.Bool("apply", false, "Apply function will apply the changes of the plan file passed with -plan, after verifying the records still match it")
+	flagAudit                      = flag.Bool("audit", false, "Audit function will report the records of the zone by their ownership and whether their hostnames are found in the cluster")
+	flagAuditDNSEndpoints          = flag.Bool("audit-dns-endpoints", false, "Also report the records of the DNSEndpoints of the zone domain that are missing from the zone in the -audit report")
+	flagAWSZoneID                  = flag.String("aws-zone-id", getEnv("MIGRATOR_AWS_ZONE_ID", ""), "AWS Route53 Zone ID")
+	flagBackupDir                  = flag.String("backup-dir", getEnv("MIGRATOR_BACKUP_DIR", "."), "Directory to write zone snapshots to before applying any changes")
+	flagCloudflareZoneName         = flag.String("cloudflare-zone-name", getEnv("MIGRATOR_CF_ZONE_NAME", ""), "Cloudflare DNS zone name")
+	flagCopy                       = flag.Bool("copy", false, "Copy function will copy the records of the old owner ID, and their TXT records, to the zone passed with -copy-to")
+	flagCopyTo                     = flag.String("copy-to", getEnv("MIGRATOR_COPY_TO", ""), "Zone to copy records to, in the form of <provider>:<zone>. GCP zones are passed as gcp:<project-id>/<zone-name> and memory zones as memory:<zone-file>. Required for -copy")
+	flagDelete                     = flag.Bool("delete", false, "Delete function will look for DNS records of an old owner and delete them. Not implemented yet")
+	flagDryRun                     = flag.Bool("dry-run", true, "Whether to dry run or actually apply changes. Defaults to true")
+	flagExternalDNSOwnerIDNew      = flag.String("external-dns-owner-id-new", getEnv("MIGRATOR_EXTERNAL_DNS_OWNER_ID_NEW", ""), "New ExternalDNS owner ID. Required for migration")
+	flagExternalDNSOwnerIDOld      = flag.String("external-dns-owner-id-old", getEnv("MIGRATOR_EXTERNAL_DNS_OWNER_ID_OLD", ""), "ExternalDNS owner ID to be replaced. Required for migration and deletion. Migration accepts a comma separated list of owner IDs to merge into the new one")
+	flagExternalDNSOwnerIDOldRegex = flag.String("external-dns-owner-id-old-regex", getEnv("MIGRATOR_EXTERNAL_DNS_OWNER_ID_OLD_REGEX", ""), "Regular expression of the ExternalDNS owner IDs to be replaced during migration, in addition to -external-dns-owner-id-old")
+	flagExternalDNSPrefix          = flag.String("external-dns-prefix", getEnv("MIGRATOR_EXTERNAL_DNS_PREFIX", ""), "Prefix of ExternalDNS TXT records, as passed to external-dns --txt-prefix. Supports the %{record_type} template. Either a prefix or a suffix is required for migration and deletion")
+	flagExternalDNSSuffix          = flag.String("external-dns-suffix", getEnv("MIGRATOR_EXTERNAL_DNS_SUFFIX", ""), "Suffix of ExternalDNS TXT records, as passed to external-dns --txt-suffix. Supports the %{record_type} template. Cannot be used together with -external-dns-prefix")
+	flagGCPZoneName                = flag.String("gcp-zone-name", getEnv("MIGRATOR_GCP_ZONE_NAME", ""), "GCP DNS zone name")
+	flagGCPProjectID               = flag.String("gcp-project-id", getEnv("MIGRATOR_GCP_PROJECT_ID", ""), "GCP project id")
+	flagJournal                    = flag.String("journal", getEnv("MIGRATOR_JOURNAL", ""), "Journal file to record applied changes to. If not set a new one is created in -backup-dir. Required for -rollback")
+	flagMemoryZoneFile             = flag.String("memory-zone-file", getEnv("MIGRATOR_MEMORY_ZONE_FILE", ""), "JSON file with the records of the memory provider zone. Changes are written back to the file")
+	flagMigrate                    = flag.Bool("migrate", false, "Migrate function will migrate owners to the a new ID")
+	flagMigrateTXTFormat           = flag.Bool("migrate-txt-format", false, "Migrate TXT format function will create the TXT records that include the record type for the records of the old owner that only have legacy TXT records")
+	flagPlan                       = flag.String("plan", getEnv("MIGRATOR_PLAN", ""), "Plan file. When set with -migrate or -delete changes are written to it instead of being applied. Required for -apply")
+	flagProvider                   = flag.String("provider", getEnv("MIGRATOR_PROVIDER", ""), "(required) The cloud provider of the DNS zones to manage records. [aws|cloudflare|gcp|memory]")
+	flagRemoveLegacyTXT            = flag.Bool("remove-legacy-txt", false, "Delete the legacy TXT records after creating the ones that include the record type. Used with -migrate-txt-format")
+	flagReport                     = flag.String("report", getEnv("MIGRATOR_REPORT", ""), "File to write the -audit report to. Defaults to stdout")
+	flagResourceNamespaceNew       = flag.String("resource-namespace-new", getEnv("MIGRATOR_RESOURCE_NAMESPACE_NEW", ""), "Namespace to move the external-dns/resource labels of -resource-namespace-old to during migration")
+	flagResourceNamespaceOld       = flag.String("resource-namespace-old", getEnv("MIGRATOR_RESOURCE_NAMESPACE_OLD", ""), "Namespace of the external-dns/resource labels to rewrite during migration")
+	flagRestore                    = flag.Bool("restore", false, "Restore function will put the external-DNS records of a zone back to the snapshot passed with -snapshot")
+	flagResume                     = flag.Bool("resume", false, "Only apply the changes of hostnames that are not recorded as done in the state file passed with -state")
+	flagRewriteResource            = flag.Bool("rewrite-resource", false, "Recompute the external-dns/resource labels from the objects of the cluster that declare the hostnames during migration")
+	flagRollback                   = flag.Bool("rollback", false, "Rollback function will revert the changes recorded in the journal passed with -journal, in reverse order")
+	flagRotateEncryptionKey        = flag.Bool("rotate-encryption-key", false, "Rotate function will re-encrypt all the encrypted external-DNS TXT records of the zone from -txt-encrypt-aes-key to -txt-encrypt-aes-key-new, keeping their owners")
+	flagSnapshot                   = flag.String("snapshot", getEnv("MIGRATOR_SNAPSHOT", ""), "Snapshot file to restore. Required for -restore")
+	flagSplitRules                 = flag.String("split-rules", getEnv("MIGRATOR_SPLIT_RULES", ""), "JSON file with the rules that pick the new owner ID of every hostname during migration, to split an owner into several")
+	flagState                      = flag.String("state", getEnv("MIGRATOR_STATE", ""), "State file to checkpoint the outcome of every hostname to. If not set a new one is created in -backup-dir. Required for -resume")
+	flagTXTEncryptAESKey           = flag.String("txt-encrypt-aes-key", getEnv("MIGRATOR_TXT_ENCRYPT_AES_KEY", ""), "AES key of external-dns encrypted TXT records, as passed to external-dns --txt-encrypt-aes-key")
+	flagTXTEncryptAESKeyNew        = flag.String("txt-encrypt-aes-key-new", getEnv("MIGRATOR_TXT_ENCRYPT_AES_KEY_NEW", ""), "AES key to encrypt rewritten TXT records with. Defaults to -txt-encrypt-aes-key")
+	flagZones                      = flag.String("zones", getEnv("MIGRATOR_ZONES", ""), "Comma separated list of zones to run -migrate, -delete and -adopt against instead of -provider, in the form of <domain>=<provider>:<zone> (e.g. example.com=aws:ZPLLMOCKBH0LL,example.net=gcp:my-project/my-zone). The domain can be left out for Cloudflare zones. Hostnames are routed to the zone with the longest matching domain")
+	flagKubeContext                = flag.String("kube-context", getEnv("MIGRATOR_KUBE_CONTEXT", ""), "Kubernetes cluster context to look for extarnal-DNS ingresses")
+	flagKubeConfigPath             = flag.String("kube-config", getEnv("MIGRATOR_KUBE_CONFIG", ""), "Path to the local kube config. If not set ~/.kube/config will be used")
 )
 
 func usage() {
@@ -43,119 +71,206 @@ func main() {
 		kubeConfigPath = filepath.Join(os.Getenv("HOME"), ".kube", "config")
 	}
 
-	if *flagProvider == "" {
-		usage()
+	var zones []dnsZone
+	if *flagZones != "" {
+		// Only the functions that discover the hostnames of the cluster run
+		// against multiple zones, and plans, journals and state files hold
+		// the changes of a single zone
+		if !*flagMigrate && !*flagDelete && !*flagAdopt {
+			usage()
+		}
+		if *flagApply || *flagRollback || *flagRotateEncryptionKey || *flagMigrateTXTFormat || *flagCopy || *flagRestore || *flagAudit {
+			usage()
+		}
+		if *flagPlan != "" || *flagJournal != "" || *flagState != "" || *flagResume {
+			usage()
+		}
+		var err error
+		zones, err = parseZones(*flagZones)
+		if err != nil {
+			log.Fatalf("Cannot create DNS provider: %v\n", err)
+		}
+	} else {
+		if *flagProvider == "" {
+			usage()
+		}
+		provider, err := newDNSProvider(*flagProvider)
+		if err != nil {
+			log.Fatalf("Cannot create DNS provider: %v\n", err)
+		}
+		zones = []dnsZone{{provider: provider}}
 	}
-	if *flagProvider == "aws" {
-		providerAWS(*flagMigrate, *flagDelete, *flagDryRun, *flagAWSZoneID, *flagExternalDNSOwnerIDOld, *flagExternalDNSOwnerIDNew, *flagExternalDNSPrefix, kubeConfigPath, *flagKubeContext)
+	provider := zones[0].provider
+	opts := runOptions{
+		dryRun:      *flagDryRun,
+		planPath:    *flagPlan,
+		backupDir:   *flagBackupDir,
+		journalPath: *flagJournal,
+		statePath:   *flagState,
+		resume:      *flagResume,
 	}
-	if *flagProvider == "cloudflare" {
-		providerCloudflare(*flagMigrate, *flagDelete, *flagDryRun, *flagCloudflareZoneName, *flagExternalDNSOwnerIDOld, *flagExternalDNSOwnerIDNew, *flagExternalDNSPrefix, kubeConfigPath, *flagKubeContext)
+	if *flagResume && *flagState == "" {
+		usage()
 	}
-	if *flagProvider == "gcp" {
-		providerGCP(*flagMigrate, *flagDelete, *flagDryRun, *flagGCPZoneName, *flagGCPProjectID, *flagExternalDNSOwnerIDOld, *flagExternalDNSOwnerIDNew, *flagExternalDNSPrefix, kubeConfigPath, *flagKubeContext)
+	registry, err := newTXTRegistry(*flagExternalDNSPrefix, *flagExternalDNSSuffix, *flagTXTEncryptAESKey, *flagTXTEncryptAESKeyNew)
+	if err != nil {
+		log.Fatalf("Invalid ExternalDNS TXT registry: %v\n", err)
 	}
 
-}
-
-func providerAWS(migrate, del, dryRun bool, zoneID, oldOwnerID, newOwnerID, prefix, kubeConfigPath, kubeContext string) {
-	kubeClient, err := kubeClientFromConfig(kubeConfigPath, kubeContext)
-	if err != nil {
-		log.Fatalf("Cannot create Kubernetes client: %v\n", err)
+	// A plan file can only hold the changes of a single function
+	if *flagPlan != "" && *flagMigrate && *flagDelete {
+		usage()
 	}
-	route53Client := newRoute53Client()
-	if migrate {
-		if newOwnerID == "" || oldOwnerID == "" || prefix == "" {
+
+	if *flagApply {
+		if *flagPlan == "" {
 			usage()
 		}
-		err := migrateAWSRoute53Owner(route53Client, kubeClient, prefix, oldOwnerID, newOwnerID, zoneID, dryRun)
-		if err != nil {
+		if err := applyPlan(provider, *flagPlan, opts); err != nil {
 			log.Fatal(err)
 		}
+		return
 	}
 
-	if del {
-		dynamicKubeClient, err := dynamicKubeClientFromConfig(kubeConfigPath, kubeContext)
-		if err != nil {
-			log.Fatalf("Cannot create dynamic Kubernetes client: %v\n", err)
-		}
-		if oldOwnerID == "" || prefix == "" {
+	if *flagRollback {
+		if *flagJournal == "" {
 			usage()
 		}
-		err = deleteAWSRoute53OwnerRecords(route53Client, kubeClient, dynamicKubeClient, prefix, oldOwnerID, zoneID, dryRun)
-		if err != nil {
+		// Do not append the rollback changes to the journal being rolled back
+		opts.journalPath = ""
+		if err := rollbackJournal(provider, *flagJournal, opts); err != nil {
 			log.Fatal(err)
 		}
+		return
 	}
 
-}
-
-func providerCloudflare(migrate, del, dryRun bool, zoneName, oldOwnerID, newOwnerID, prefix, kubeConfigPath, kubeContext string) {
-	kubeClient, err := kubeClientFromConfig(kubeConfigPath, kubeContext)
-	if err != nil {
-		log.Fatalf("Cannot create Kubernetes client: %v\n", err)
-	}
-	apiKey := getEnv("CLOUDFLARE_API_KEY", "")
-	email := getEnv("CLOUDFLARE_EMAIL", "")
-	cloudflareAPIClient, err := newCloudflareAPIClient(apiKey, email)
-	if err != nil {
-		log.Fatalf("Cannot create Cloudflare API client from key: %v\n", err)
-	}
-	if migrate {
-		if newOwnerID == "" || oldOwnerID == "" || prefix == "" || zoneName == "" {
+	if *flagRotateEncryptionKey {
+		if *flagTXTEncryptAESKey == "" || *flagTXTEncryptAESKeyNew == "" {
 			usage()
 		}
-		err := migrateCloudflareRecordOwner(cloudflareAPIClient, kubeClient, prefix, oldOwnerID, newOwnerID, zoneName, dryRun)
-		if err != nil {
+		if err := rotateEncryptionKey(provider, registry, opts); err != nil {
 			log.Fatal(err)
 		}
+		return
 	}
-	if del {
-		dynamicKubeClient, err := dynamicKubeClientFromConfig(kubeConfigPath, kubeContext)
-		if err != nil {
-			log.Fatalf("Cannot create dynamic Kubernetes client: %v\n", err)
+
+	if *flagMigrateTXTFormat {
+		if *flagExternalDNSOwnerIDOld == "" || !registry.isSet() {
+			usage()
 		}
-		if oldOwnerID == "" || prefix == "" || zoneName == "" {
+		if err := migrateTXTFormat(provider, registry, *flagExternalDNSOwnerIDOld, *flagRemoveLegacyTXT, opts); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *flagCopy {
+		if *flagCopyTo == "" || *flagExternalDNSOwnerIDOld == "" || !registry.isSet() {
 			usage()
 		}
-		err = deleteCloudflareOwnerRecords(cloudflareAPIClient, kubeClient, dynamicKubeClient, prefix, oldOwnerID, zoneName, dryRun)
+		target, err := parseZoneSpec(*flagCopyTo)
 		if err != nil {
+			log.Fatalf("Cannot create DNS provider: %v\n", err)
+		}
+		if err := copyOwnerRecords(provider, target, registry, *flagExternalDNSOwnerIDOld, *flagExternalDNSOwnerIDNew, opts); err != nil {
 			log.Fatal(err)
 		}
+		return
 	}
 
-}
+	if *flagRestore {
+		if *flagSnapshot == "" || !registry.isSet() {
+			usage()
+		}
+		if err := restoreSnapshot(provider, *flagSnapshot, registry, opts); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-func providerGCP(migrate, del, dryRun bool, zoneName, projectID, oldOwnerID, newOwnerID, prefix, kubeConfigPath, kubeContext string) {
-	kubeClient, err := kubeClientFromConfig(kubeConfigPath, kubeContext)
+	kubeClient, err := kubeClientFromConfig(kubeConfigPath, *flagKubeContext)
 	if err != nil {
 		log.Fatalf("Cannot create Kubernetes client: %v\n", err)
 	}
-	client, err := newGCPDNSClient()
+	dynamicKubeClient, err := dynamicKubeClientFromConfig(kubeConfigPath, *flagKubeContext)
 	if err != nil {
-		log.Fatalf("Cannot create GCP client: %v\n", err)
+		log.Fatalf("Cannot create dynamic Kubernetes client: %v\n", err)
 	}
-	if migrate {
-		if newOwnerID == "" || oldOwnerID == "" || prefix == "" || zoneName == "" || projectID == "" {
+
+	if *flagAudit {
+		if *flagExternalDNSOwnerIDOld == "" || !registry.isSet() {
+			usage()
+		}
+		if err := auditZone(provider, kubeClient, dynamicKubeClient, registry, *flagExternalDNSOwnerIDOld, *flagReport, *flagAuditDNSEndpoints); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *flagAdopt {
+		if *flagExternalDNSOwnerIDNew == "" || !registry.isSet() {
 			usage()
 		}
-		err := migrateGCPDNSOwner(client, kubeClient, prefix, oldOwnerID, newOwnerID, projectID, zoneName, dryRun)
+		hostnames, err := externalDNSKubeHostnames(kubeClient, dynamicKubeClient)
 		if err != nil {
 			log.Fatal(err)
 		}
+		err = runZones(zones, hostnames, opts, func(provider DNSProvider, hostnames []kubeHostname, opts runOptions) error {
+			return adoptRecords(provider, hostnames, registry, *flagExternalDNSOwnerIDNew, opts)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	if del {
-		dynamicKubeClient, err := dynamicKubeClientFromConfig(kubeConfigPath, kubeContext)
+
+	if *flagMigrate {
+		oldOwners, err := newOwnerSelector(*flagExternalDNSOwnerIDOld, *flagExternalDNSOwnerIDOldRegex)
 		if err != nil {
-			log.Fatalf("Cannot create dynamic Kubernetes client: %v\n", err)
+			log.Fatal(err)
+		}
+		migration := ownerMigration{
+			oldOwners:            oldOwners,
+			newOwner:             *flagExternalDNSOwnerIDNew,
+			resourceNamespaceOld: *flagResourceNamespaceOld,
+			resourceNamespaceNew: *flagResourceNamespaceNew,
+			rewriteResource:      *flagRewriteResource,
+		}
+		if !migration.oldOwners.isSet() || !registry.isSet() {
+			usage()
+		}
+		if *flagSplitRules != "" {
+			rules, err := readSplitRules(*flagSplitRules)
+			if err != nil {
+				log.Fatal(err)
+			}
+			migration.splitRules = rules
 		}
-		if oldOwnerID == "" || prefix == "" || zoneName == "" || projectID == "" {
+		if migration.newOwner == "" && migration.splitRules == nil && !migration.rewriteResource && (migration.resourceNamespaceOld == "" || migration.resourceNamespaceNew == "") {
 			usage()
 		}
-		err = deleteGCPDNSOwnerRecords(client, kubeClient, dynamicKubeClient, prefix, oldOwnerID, projectID, zoneName, dryRun)
+		hostnames, err := externalDNSKubeHostnames(kubeClient, dynamicKubeClient)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = runZones(zones, hostnames, opts, func(provider DNSProvider, hostnames []kubeHostname, opts runOptions) error {
+			return migrateOwner(provider, hostnames, registry, migration, opts)
+		})
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
 
+	if *flagDelete {
+		if *flagExternalDNSOwnerIDOld == "" || !registry.isSet() {
+			usage()
+		}
+		// Deletion is driven by the records of every zone
+		err = runZones(zones, nil, opts, func(provider DNSProvider, _ []kubeHostname, opts runOptions) error {
+			return deleteOwnerRecords(provider, kubeClient, dynamicKubeClient, registry, *flagExternalDNSOwnerIDOld, opts)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// memoryProvider implements DNSProvider on top of an in-memory list of
+// records. It can be seeded directly or from a zone file, so that migrations
+// can be rehearsed without touching a real DNS service. When backed by a file
+// every change is written back to it.
+type memoryProvider struct {
+	zone    string
+	path    string
+	records []Record
+	nextID  int
+}
+
+func newMemoryProvider(zone string, records ...Record) *memoryProvider {
+	p := &memoryProvider{zone: zone}
+	for _, record := range records {
+		p.add(record)
+	}
+	return p
+}
+
+// newMemoryProviderFromFile returns a memoryProvider seeded with the JSON list
+// of records found in path.
+func newMemoryProviderFromFile(path string) (*memoryProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zone file: %w", err)
+	}
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse zone file %s: %w", path, err)
+	}
+	p := newMemoryProvider(path, records...)
+	p.path = path
+	return p, nil
+}
+
+func (p *memoryProvider) Name() string {
+	return "memory"
+}
+
+func (p *memoryProvider) Zone() string {
+	return p.zone
+}
+
+func (p *memoryProvider) Records() ([]Record, error) {
+	records := make([]Record, len(p.records))
+	for i, record := range p.records {
+		records[i] = copyRecord(record)
+	}
+	return records, nil
+}
+
+func (p *memoryProvider) UpdateRecord(record Record, values []string) error {
+	i := findRecord(p.records, record)
+	if i < 0 {
+		return fmt.Errorf("record %s Type: %s not found", record.Name, record.Type)
+	}
+	p.records[i].Values = append([]string{}, values...)
+	return p.save()
+}
+
+func (p *memoryProvider) DeleteRecord(record Record) error {
+	i := findRecord(p.records, record)
+	if i < 0 {
+		return fmt.Errorf("record %s Type: %s not found", record.Name, record.Type)
+	}
+	p.records = append(p.records[:i], p.records[i+1:]...)
+	return p.save()
+}
+
+func (p *memoryProvider) CreateRecord(record Record) error {
+	record.ID = ""
+	if findRecord(p.records, record) >= 0 {
+		return fmt.Errorf("record %s Type: %s already exists", record.Name, record.Type)
+	}
+	p.add(record)
+	return p.save()
+}
+
+// add appends a copy of the record to the zone, assigning it an ID if it has
+// none
+func (p *memoryProvider) add(record Record) {
+	record = copyRecord(record)
+	record.Name = sanitizeDNSAddress(record.Name)
+	if record.ID == "" {
+		p.nextID++
+		record.ID = fmt.Sprintf("%d", p.nextID)
+	}
+	p.records = append(p.records, record)
+}
+
+// save writes the records back to the zone file, if the provider has one
+func (p *memoryProvider) save() error {
+	if p.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(p.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode zone file: %w", err)
+	}
+	if err := os.WriteFile(p.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write zone file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ownerSelector matches a list of owner IDs and/or the owner IDs that match a
+// pattern
+type ownerSelector struct {
+	owners  []string
+	pattern *regexp.Regexp
+}
+
+// newOwnerSelector returns an ownerSelector from a comma separated list of
+// owner IDs and a regular expression, either of which can be empty
+func newOwnerSelector(owners, pattern string) (ownerSelector, error) {
+	var s ownerSelector
+	for _, owner := range strings.Split(owners, ",") {
+		if owner = strings.TrimSpace(owner); owner != "" {
+			s.owners = append(s.owners, owner)
+		}
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return s, fmt.Errorf("invalid owner ID pattern: %v", err)
+		}
+		s.pattern = re
+	}
+	return s, nil
+}
+
+// isSet returns true if the selector can match any owner ID
+func (s ownerSelector) isSet() bool {
+	return len(s.owners) > 0 || s.pattern != nil
+}
+
+// matches returns true if the owner ID is selected
+func (s ownerSelector) matches(owner string) bool {
+	return slices.Contains(s.owners, owner) || (s.pattern != nil && s.pattern.MatchString(owner))
+}
+
+// ownerMigration describes how the labels of the TXT records owned by the old
+// owner IDs are rewritten
+type ownerMigration struct {
+	oldOwners ownerSelector
+	// newOwner replaces the old owner ID, if set
+	newOwner string
+	// resourceNamespaceOld and resourceNamespaceNew move the resource labels
+	// of one namespace to another, if set
+	resourceNamespaceOld string
+	resourceNamespaceNew string
+	// rewriteResource recomputes the resource labels from the objects of the
+	// cluster that declare the hostnames
+	rewriteResource bool
+	// splitRules pick the new owner of every hostname instead of newOwner,
+	// if set
+	splitRules []splitRule
+}
+
+// rewrite returns a function that applies the migration to the labels of a
+// TXT record. resource is the object of the cluster that declares the
+// hostname of the record, if known.
+func (m ownerMigration) rewrite(resource string) func(labels) {
+	return func(l labels) {
+		if m.newOwner != "" {
+			l[labelOwner] = m.newOwner
+		}
+		if m.resourceNamespaceNew != "" {
+			// Resource labels are in the form of <kind>/<namespace>/<name>
+			parts := strings.SplitN(l[labelResource], "/", 3)
+			if len(parts) == 3 && parts[1] == m.resourceNamespaceOld {
+				parts[1] = m.resourceNamespaceNew
+				l[labelResource] = strings.Join(parts, "/")
+			}
+		}
+		if m.rewriteResource && resource != "" {
+			l[labelResource] = resource
+		}
+	}
+}
+
+// migrateOwner will look for the external-DNS TXT records of the hostnames
+// found in the cluster and rewrite the labels of the ones owned by the old
+// owner ID.
+func migrateOwner(provider DNSProvider, hostnames []kubeHostname, registry txtRegistry, migration ownerMigration, opts runOptions) error {
+	records, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+	changes, err := planMigration(hostnames, records, registry, migration)
+	if err != nil {
+		return err
+	}
+	printMigrationSummary(changes, registry)
+	return executeChanges(provider, records, changes, opts)
+}
+
+// printMigrationSummary prints the number of TXT record values that are
+// rewritten for every old owner ID
+func printMigrationSummary(changes []change, registry txtRegistry) {
+	counts := map[string]int{}
+	for _, c := range changes {
+		if c.Before == nil || c.After == nil {
+			continue
+		}
+		for i, value := range c.Before.Values {
+			if i < len(c.After.Values) && c.After.Values[i] == value {
+				continue
+			}
+			if owner, ok := registry.owner(value); ok {
+				counts[owner]++
+			}
+		}
+	}
+	owners := slices.Sorted(maps.Keys(counts))
+	for _, owner := range owners {
+		fmt.Printf("Migrating %d TXT records of owner: %s\n", counts[owner], owner)
+	}
+}
+
+// planMigration returns the changes needed to rewrite the labels of the TXT
+// records of the hostnames that are owned by the old owner ID.
+func planMigration(hostnames []kubeHostname, records []Record, registry txtRegistry, migration ownerMigration) ([]change, error) {
+	var changes []change
+	resources := hostnameResources(hostnames)
+	recordTypes := hostnameRecordTypes(hostnames)
+	owners := splitOwners(hostnames, migration.splitRules)
+	planned := map[string]bool{}
+	for _, h := range hostnames {
+		hostname := h.Hostname
+		if planned[sanitizeDNSAddress(hostname)] {
+			continue
+		}
+		planned[sanitizeDNSAddress(hostname)] = true
+		m := migration
+		if len(migration.splitRules) > 0 {
+			m.newOwner = owners[sanitizeDNSAddress(hostname)]
+		}
+		resource := resources[sanitizeDNSAddress(hostname)]
+		if migration.rewriteResource && resource == "" {
+			fmt.Printf("Cannot rewrite resource label of: %s, declared by more than one object\n", hostname)
+		}
+		for _, r := range lookupExternalDNSTXTRecords(hostname, registry, records, recordTypes[sanitizeDNSAddress(hostname)]...) {
+			newValues := make([]string, len(r.Values))
+			changed := false
+			for i, value := range r.Values {
+				newValues[i] = value
+				if owner, ok := registry.owner(value); !ok || !migration.oldOwners.matches(owner) {
+					continue
+				}
+				v, rewritten, err := registry.rewriteLabels(value, m.rewrite(resource))
+				if err != nil {
+					return nil, fmt.Errorf("Cannot rewrite record: %s, %v", r.Name, err)
+				}
+				newValues[i] = v
+				changed = changed || rewritten
+			}
+			if !changed {
+				continue
+			}
+			changes = append(changes, updateChange(hostname, r, newValues))
+		}
+	}
+	return changes, nil
+}
+
+// deleteOwnerRecords will delete all the records owned by the owner ID,
+// together with their TXT ownership records, unless their hostnames are still
+// found in the cluster.
+func deleteOwnerRecords(provider DNSProvider, kubeClient kubernetes.Interface, dynamicKubeClient dynamic.Interface, registry txtRegistry, owner string, opts runOptions) error {
+	hostnames, err := clusterHostnames(kubeClient, dynamicKubeClient)
+	if err != nil {
+		return err
+	}
+	inUse := hostnameObjects(hostnames)
+
+	allRecords, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+
+	var changes []change
+	var toDeleteHostnames []string
+	toDeleteRecords := map[string][]Record{}
+	for _, record := range ownedRecordsList(allRecords, registry, owner) {
+		if _, ok := toDeleteRecords[record.Name]; !ok {
+			toDeleteHostnames = append(toDeleteHostnames, record.Name)
+		}
+		toDeleteRecords[record.Name] = append(toDeleteRecords[record.Name], record)
+	}
+	for _, hostname := range toDeleteHostnames {
+		// Skip if the hostname is still found in the cluster
+		if objects := inUse[hostname]; len(objects) > 0 {
+			fmt.Printf("Skipping record: %s found in the cluster in: %s\n", hostname, strings.Join(objects, ", "))
+			continue
+		}
+		// Delete records
+		for _, record := range toDeleteRecords[hostname] {
+			changes = append(changes, deleteChange(hostname, record))
+		}
+		// Delete TXT ownership records, once for all the record types of
+		// the hostname
+		for _, txt := range lookupExternalDNSTXTRecords(hostname, registry, allRecords) {
+			changes = append(changes, deleteChange(hostname, txt))
+		}
+	}
+
+	// Delete TXT ownership records left behind by records deleted earlier,
+	// or by the records deleted above
+	remaining := remainingRecords(allRecords, changes, recordPerValue(provider))
+	for _, txt := range danglingTXTRecords(remaining, registry) {
+		if !ownedTXTRecord(txt, registry, owner) {
+			continue
+		}
+		// Skip if the TXT record belongs to a hostname still found in the
+		// cluster, its record will be created again by external-DNS
+		if hostname, ok := txtRecordHostname(txt, registry, hostnamesList(hostnames)); ok {
+			fmt.Printf("Skipping dangling TXT record: %s of %s found in the cluster\n", txt.Name, hostname)
+			continue
+		}
+		changes = append(changes, deleteChange(txt.Name, txt))
+	}
+	return executeChanges(provider, allRecords, changes, opts)
+}
+
+// remainingRecords returns the records left in the zone once the delete
+// changes are applied
+func remainingRecords(records []Record, changes []change, perValue bool) []Record {
+	remaining := slices.Clone(records)
+	for _, c := range changes {
+		if c.Action != actionDelete {
+			continue
+		}
+		if i := matchRecord(remaining, *c.Before, perValue); i >= 0 {
+			remaining = slices.Delete(remaining, i, i+1)
+		}
+	}
+	return remaining
+}
+
+// danglingTXTRecords returns the external-DNS TXT records that do not belong
+// to any record of the zone
+func danglingTXTRecords(records []Record, registry txtRegistry) []Record {
+	pointed := map[string]bool{}
+	for _, record := range records {
+		if record.Type == "TXT" {
+			continue
+		}
+		for _, name := range registry.txtNames(record.Name, record.Type) {
+			pointed[sanitizeDNSAddress(name)] = true
+		}
+	}
+	var dangling []Record
+	for _, record := range records {
+		if record.Type == "TXT" && !pointed[record.Name] && registryTXTRecord(record, registry) {
+			dangling = append(dangling, record)
+		}
+	}
+	return dangling
+}
+
+// txtRecordHostname returns the hostname of the list that the TXT record is
+// named after, for any of the record types external-DNS can own
+func txtRecordHostname(txt Record, registry txtRegistry, hostnames []string) (string, bool) {
+	for _, hostname := range hostnames {
+		for _, recordType := range adoptableTypes {
+			for _, name := range registry.txtNames(hostname, recordType) {
+				if sanitizeDNSAddress(name) == txt.Name {
+					return hostname, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// ownedRecordsList expects a list of records, a TXT registry and an owner ID and
+// will return a list of records, excluding TXT ones, that belong to the owner
+// ID. The SOA and NS records of the zone apex are never owned, even if the
+// apex has records managed by external-DNS.
+func ownedRecordsList(records []Record, registry txtRegistry, owner string) []Record {
+	ownedRecords := []Record{}
+	var apex string
+	if i := slices.IndexFunc(records, func(r Record) bool { return r.Type == "SOA" }); i >= 0 {
+		apex = records[i].Name
+	}
+	for _, record := range records {
+		if record.Type == "TXT" || record.Type == "SOA" || (record.Type == "NS" && record.Name == apex) {
+			continue
+		}
+		owned := false
+		for _, r := range lookupExternalDNSTXTRecords(record.Name, registry, records) {
+			for _, value := range r.Values {
+				if registry.verifyOwner(value, owner) {
+					owned = true
+					break
+				}
+			}
+		}
+		if owned {
+			ownedRecords = append(ownedRecords, record)
+		}
+
+	}
+	return ownedRecords
+}
+
+// lookupExternalDNSTXTRecords returns all the TXT records found for a hostname.
+// These are the TXT records named after every type of record found for the
+// hostname, and after the extra record types passed, e.g. the ones declared by
+// DNSEndpoints. Hostnames without any of those have none.
+func lookupExternalDNSTXTRecords(hostname string, registry txtRegistry, records []Record, recordTypes ...string) []Record {
+	var externalDNSRecords []Record
+	recordTypes = append(recordTypesOf(hostname, records), recordTypes...)
+	var txtNames []string
+	for _, recordType := range recordTypes {
+		txtNames = append(txtNames, registry.txtNames(hostname, recordType)...)
+	}
+	if len(txtNames) == 0 {
+		return externalDNSRecords
+	}
+	for _, record := range records {
+		if record.Type == "TXT" && slices.Contains(txtNames, record.Name) {
+			externalDNSRecords = append(externalDNSRecords, record)
+		}
+	}
+	return externalDNSRecords
+}
+
+// recordTypesOf returns the types of the records of a hostname found in the
+// passed list of records, excluding TXT ones
+func recordTypesOf(hostname string, records []Record) []string {
+	var recordTypes []string
+	for _, record := range records {
+		if record.Name == sanitizeDNSAddress(hostname) && record.Type != "TXT" && !slices.Contains(recordTypes, record.Type) {
+			recordTypes = append(recordTypes, record.Type)
+		}
+	}
+	return recordTypes
+}
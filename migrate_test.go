@@ -0,0 +1,313 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testRecord returns a record of the zone with a TTL of 300
+func testRecord(name, recordType string, values ...string) Record {
+	return Record{Name: sanitizeDNSAddress(name), Type: recordType, TTL: 300, Values: values}
+}
+
+// registryValue returns a quoted TXT registry value of the owner, with the
+// resource label if set
+func registryValue(owner, resource string) string {
+	l := labels{labelOwner: owner}
+	if resource != "" {
+		l[labelResource] = resource
+	}
+	return quoteTXT(l.String())
+}
+
+// zoneRecords returns the values of the records of the zone keyed by
+// <name> <type>
+func zoneRecords(t *testing.T, provider DNSProvider) map[string][]string {
+	t.Helper()
+	records, err := provider.Records()
+	if err != nil {
+		t.Fatalf("failed to list records: %v", err)
+	}
+	return recordValues(records)
+}
+
+// recordValues returns the values of the records keyed by <name> <type>
+func recordValues(records []Record) map[string][]string {
+	values := map[string][]string{}
+	for _, record := range records {
+		key := record.Name + " " + record.Type
+		values[key] = append(values[key], record.Values...)
+	}
+	return values
+}
+
+// changeList returns the changes in the form of <action> <name> <type>
+// <values>, for comparison. The ID of the changed record follows the name, if
+// it has one.
+func changeList(changes []change) []string {
+	var list []string
+	for _, c := range changes {
+		record := c.After
+		if c.Action == actionDelete {
+			record = c.Before
+		}
+		s := c.Action + " " + record.Name
+		if c.Before != nil && c.Before.ID != "" {
+			s += " #" + c.Before.ID
+		}
+		s += " " + record.Type
+		for _, value := range record.Values {
+			s += " " + value
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+func newTestRegistry(t *testing.T, prefix, suffix string) txtRegistry {
+	t.Helper()
+	registry, err := newTXTRegistry(prefix, suffix, "", "")
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+	return registry
+}
+
+func newTestOwnerSelector(t *testing.T, owners, pattern string) ownerSelector {
+	t.Helper()
+	s, err := newOwnerSelector(owners, pattern)
+	if err != nil {
+		t.Fatalf("failed to create owner selector: %v", err)
+	}
+	return s
+}
+
+func TestPlanMigration(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	records := []Record{
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		testRecord("infra-a-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		testRecord("b.example.com", "CNAME", "lb.example.net."),
+		testRecord("infra-b.example.com", "TXT", registryValue("other", "ingress/ns/b")),
+		testRecord("c.example.com", "A", "1.1.1.1"),
+		testRecord("infra-c.example.com", "TXT", registryValue("old", "ingress/ns/c"), `"v=spf1 -all"`),
+		testRecord("e.example.com", "A", "1.1.1.1"),
+		testRecord("e.example.com", "AAAA", "::1"),
+		testRecord("infra-a-e.example.com", "TXT", registryValue("old", "ingress/ns/e")),
+		testRecord("infra-aaaa-e.example.com", "TXT", registryValue("old", "ingress/ns/e")),
+		testRecord("infra-cname-f.example.com", "TXT", registryValue("old", "crd/ns/f")),
+	}
+	tests := []struct {
+		name      string
+		hostnames []kubeHostname
+		migration ownerMigration
+		want      []string
+	}{
+		{
+			name:      "rewrites the legacy and typed TXT records of the old owner",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Resource: "ingress/ns/a"}},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"},
+			want: []string{
+				"update infra-a.example.com. TXT " + registryValue("new", "ingress/ns/a"),
+				"update infra-a-a.example.com. TXT " + registryValue("new", "ingress/ns/a"),
+			},
+		},
+		{
+			name:      "leaves the TXT records of other owners",
+			hostnames: []kubeHostname{{Hostname: "b.example.com", Resource: "ingress/ns/b"}},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"},
+		},
+		{
+			name:      "rewrites the typed TXT records of every record type of the hostname",
+			hostnames: []kubeHostname{{Hostname: "e.example.com", Resource: "ingress/ns/e"}},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"},
+			want: []string{
+				"update infra-a-e.example.com. TXT " + registryValue("new", "ingress/ns/e"),
+				"update infra-aaaa-e.example.com. TXT " + registryValue("new", "ingress/ns/e"),
+			},
+		},
+		{
+			name:      "rewrites the typed TXT records of the record types declared by DNSEndpoints",
+			hostnames: []kubeHostname{{Hostname: "f.example.com", Resource: "crd/ns/f", RecordType: "CNAME"}},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"},
+			want: []string{
+				"update infra-cname-f.example.com. TXT " + registryValue("new", "crd/ns/f"),
+			},
+		},
+		{
+			name:      "skips hostnames without records",
+			hostnames: []kubeHostname{{Hostname: "d.example.com", Resource: "ingress/ns/d"}},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"},
+		},
+		{
+			name: "plans hostnames declared by several objects once",
+			hostnames: []kubeHostname{
+				{Hostname: "c.example.com", Resource: "ingress/ns/c"},
+				{Hostname: "c.example.com.", Resource: "service/ns/c"},
+			},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"},
+			want: []string{
+				"update infra-c.example.com. TXT " + registryValue("new", "ingress/ns/c") + ` "v=spf1 -all"`,
+			},
+		},
+		{
+			name: "merges several old owners",
+			hostnames: []kubeHostname{
+				{Hostname: "a.example.com", Resource: "ingress/ns/a"},
+				{Hostname: "b.example.com", Resource: "ingress/ns/b"},
+			},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old, other", ""), newOwner: "new"},
+			want: []string{
+				"update infra-a.example.com. TXT " + registryValue("new", "ingress/ns/a"),
+				"update infra-a-a.example.com. TXT " + registryValue("new", "ingress/ns/a"),
+				"update infra-b.example.com. TXT " + registryValue("new", "ingress/ns/b"),
+			},
+		},
+		{
+			name: "merges the old owners that match a pattern",
+			hostnames: []kubeHostname{
+				{Hostname: "a.example.com", Resource: "ingress/ns/a"},
+				{Hostname: "b.example.com", Resource: "ingress/ns/b"},
+			},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "", "^oth"), newOwner: "new"},
+			want: []string{
+				"update infra-b.example.com. TXT " + registryValue("new", "ingress/ns/b"),
+			},
+		},
+		{
+			name:      "moves the resource labels of a namespace",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Resource: "ingress/ns/a"}},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), resourceNamespaceOld: "ns", resourceNamespaceNew: "apps"},
+			want: []string{
+				"update infra-a.example.com. TXT " + registryValue("old", "ingress/apps/a"),
+				"update infra-a-a.example.com. TXT " + registryValue("old", "ingress/apps/a"),
+			},
+		},
+		{
+			name:      "leaves the resource labels of other namespaces",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Resource: "ingress/ns/a"}},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), resourceNamespaceOld: "sys", resourceNamespaceNew: "apps"},
+		},
+		{
+			name:      "rewrites the resource labels from the cluster objects",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Resource: "httproute/apps/a"}},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new", rewriteResource: true},
+			want: []string{
+				"update infra-a.example.com. TXT " + registryValue("new", "httproute/apps/a"),
+				"update infra-a-a.example.com. TXT " + registryValue("new", "httproute/apps/a"),
+			},
+		},
+		{
+			name: "leaves the resource labels of hostnames declared by several objects",
+			hostnames: []kubeHostname{
+				{Hostname: "a.example.com", Resource: "httproute/apps/a"},
+				{Hostname: "a.example.com", Resource: "service/apps/a"},
+			},
+			migration: ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), rewriteResource: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := planMigration(tt.hostnames, records, registry, tt.migration)
+			if err != nil {
+				t.Fatalf("planMigration() error = %v", err)
+			}
+			if got := changeList(changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planMigration() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOwnerSelector(t *testing.T) {
+	tests := []struct {
+		name    string
+		owners  string
+		pattern string
+		owner   string
+		want    bool
+	}{
+		{name: "listed owner", owners: "a, b", owner: "b", want: true},
+		{name: "unlisted owner", owners: "a,b", owner: "c"},
+		{name: "owner matching the pattern", pattern: "^exp-[0-9]+-", owner: "exp-1-merit", want: true},
+		{name: "owner not matching the pattern", pattern: "^exp-[0-9]+-", owner: "prod-aws"},
+		{name: "listed owner not matching the pattern", owners: "prod-aws", pattern: "^exp-", owner: "prod-aws", want: true},
+		{name: "empty selector", owner: "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestOwnerSelector(t, tt.owners, tt.pattern)
+			if got := s.matches(tt.owner); got != tt.want {
+				t.Errorf("matches(%s) = %v, want %v", tt.owner, got, tt.want)
+			}
+		})
+	}
+	if _, err := newOwnerSelector("", "("); err == nil {
+		t.Error("newOwnerSelector() error = nil for an invalid pattern")
+	}
+	if s := newTestOwnerSelector(t, " , ", ""); s.isSet() {
+		t.Error("isSet() = true for an empty selector")
+	}
+}
+
+func TestMigrateOwner(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		testRecord("infra-a-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("other", "ingress/ns/b")),
+		testRecord("c.example.com", "A", "3.3.3.3"),
+		testRecord("infra-c.example.com", "TXT", registryValue("old", "ingress/ns/c")),
+	)
+	annotations := map[string]string{"external-dns.alpha.kubernetes.io/target": "lb.example.net"}
+	kubeClient := fake.NewClientset(
+		ingress("ns", "a", annotations, "a.example.com"),
+		ingress("ns", "b", annotations, "b.example.com"),
+		// Not annotated for external-dns
+		ingress("ns", "c", nil, "c.example.com"),
+	)
+	dynamicKubeClient := newFakeDynamicClient(t)
+
+	hostnames, err := externalDNSKubeHostnames(kubeClient, dynamicKubeClient)
+	if err != nil {
+		t.Fatalf("externalDNSKubeHostnames() error = %v", err)
+	}
+	migration := ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"}
+	if err := migrateOwner(provider, hostnames, registry, migration, runOptions{backupDir: t.TempDir()}); err != nil {
+		t.Fatalf("migrateOwner() error = %v", err)
+	}
+	want := map[string][]string{
+		"a.example.com. A":           {"1.1.1.1"},
+		"infra-a.example.com. TXT":   {registryValue("new", "ingress/ns/a")},
+		"infra-a-a.example.com. TXT": {registryValue("new", "ingress/ns/a")},
+		"b.example.com. A":           {"2.2.2.2"},
+		"infra-b.example.com. TXT":   {registryValue("other", "ingress/ns/b")},
+		"c.example.com. A":           {"3.3.3.3"},
+		"infra-c.example.com. TXT":   {registryValue("old", "ingress/ns/c")},
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
+
+func TestMigrateOwnerDryRun(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	provider := newMemoryProvider("example.com",
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+	)
+	want := zoneRecords(t, provider)
+	hostnames := []kubeHostname{{Hostname: "a.example.com", Resource: "ingress/ns/a"}}
+	migration := ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"}
+	if err := migrateOwner(provider, hostnames, registry, migration, runOptions{dryRun: true}); err != nil {
+		t.Fatalf("migrateOwner() error = %v", err)
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+)
+
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionDelete = "delete"
+)
+
+// change is a single modification of a record in a zone. Before holds the
+// record as it was found in the zone and After the record as it is meant to be
+// after the change is applied.
+type change struct {
+	Action   string  `json:"action"`
+	Hostname string  `json:"hostname"`
+	Before   *Record `json:"before,omitempty"`
+	After    *Record `json:"after,omitempty"`
+}
+
+// plan is the machine readable list of changes to apply to a zone
+type plan struct {
+	Provider string   `json:"provider"`
+	Zone     string   `json:"zone"`
+	Changes  []change `json:"changes"`
+}
+
+// runOptions control what happens with the changes computed by a run
+type runOptions struct {
+	dryRun bool
+	// planPath is the file to write the changes to instead of applying them
+	planPath string
+	// backupDir is the directory zone snapshots and journals are written to
+	// before changes are applied
+	backupDir string
+	// journalPath is the journal file applied changes are appended to. A new
+	// journal is created in backupDir if it is empty
+	journalPath string
+	// statePath is the file the outcome of every hostname is checkpointed to.
+	// A new state file is created in backupDir if it is empty
+	statePath string
+	// resume skips the hostnames recorded as done in the state file
+	resume bool
+	// summary collects the outcome of the changes of every zone, if set
+	summary *runSummary
+}
+
+func updateChange(hostname string, record Record, values []string) change {
+	before := copyRecord(record)
+	after := copyRecord(record)
+	after.Values = append([]string(nil), values...)
+	return change{Action: actionUpdate, Hostname: sanitizeDNSAddress(hostname), Before: &before, After: &after}
+}
+
+func deleteChange(hostname string, record Record) change {
+	before := copyRecord(record)
+	return change{Action: actionDelete, Hostname: sanitizeDNSAddress(hostname), Before: &before}
+}
+
+func createChange(hostname string, record Record) change {
+	after := copyRecord(record)
+	return change{Action: actionCreate, Hostname: sanitizeDNSAddress(hostname), After: &after}
+}
+
+// executeChanges either writes the changes to a plan file or applies them to
+// the zone, depending on the run options. The records are the current records
+// of the zone the changes were computed from.
+func executeChanges(provider DNSProvider, records []Record, changes []change, opts runOptions) error {
+	if opts.planPath != "" {
+		p := plan{
+			Provider: provider.Name(),
+			Zone:     provider.Zone(),
+			Changes:  changes,
+		}
+		if err := writePlan(opts.planPath, p); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d changes to plan: %s\n", len(changes), opts.planPath)
+		return nil
+	}
+	return applyChanges(provider, records, changes, opts)
+}
+
+// applyChanges applies the changes to the zone one by one, after taking a
+// snapshot of the zone records. Applied changes are recorded in a journal and
+// the outcome of every hostname in the state file. Once a change fails the
+// rest of the changes of the same hostname are skipped, but the changes of
+// other hostnames are still applied. An error is returned if any change
+// failed.
+func applyChanges(provider DNSProvider, records []Record, changes []change, opts runOptions) error {
+	dryRun := opts.dryRun
+	var state *runState
+	if opts.resume {
+		var err error
+		changes, state, err = resumeChanges(provider, changes, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	var j *journal
+	if !dryRun && len(changes) > 0 {
+		path, err := writeSnapshot(opts.backupDir, provider, records)
+		if err != nil {
+			return fmt.Errorf("Cannot snapshot %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+		}
+		fmt.Printf("Wrote snapshot of %d records to: %s\n", len(records), path)
+		j, err = openJournal(opts.journalPath, opts.backupDir, provider)
+		if err != nil {
+			return fmt.Errorf("Cannot open journal: %v", err)
+		}
+		defer j.close()
+		fmt.Printf("Recording applied changes to journal: %s\n", j.path())
+		if state == nil {
+			state, err = loadState(opts.statePath, opts.backupDir, provider)
+			if err != nil {
+				return fmt.Errorf("Cannot load state: %v", err)
+			}
+		}
+		fmt.Printf("Recording hostname outcomes to state: %s\n", state.path)
+	}
+
+	// A hostname is only done once its last change is applied
+	lastChanges := map[string]int{}
+	for i, c := range changes {
+		lastChanges[c.Hostname] = i
+	}
+	failedHostnames := map[string]bool{}
+	var applied, failed, skipped int
+	for i, c := range changes {
+		var msg string
+		switch c.Action {
+		case actionUpdate:
+			msg = fmt.Sprintf("Updating record: %s Type: %s with values: %s", c.After.Name, c.After.Type, c.After.Values)
+		case actionDelete:
+			msg = fmt.Sprintf("Deleting record: %s Type: %s", c.Before.Name, c.Before.Type)
+		case actionCreate:
+			msg = fmt.Sprintf("Creating record: %s Type: %s with values: %s", c.After.Name, c.After.Type, c.After.Values)
+		}
+		if dryRun {
+			msg += " (dry run)"
+		}
+		if failedHostnames[c.Hostname] {
+			fmt.Printf("Skipping change after a previous failure for hostname: %s\n", c.Hostname)
+			skipped++
+			continue
+		}
+		fmt.Println(msg)
+		if dryRun {
+			continue
+		}
+		outcome := outcomeDone
+		if i < lastChanges[c.Hostname] {
+			outcome = outcomeInProgress
+		}
+		if err := applyChange(provider, c); err != nil {
+			log.Printf("Failed to %s record: %v", c.Action, err)
+			failedHostnames[c.Hostname] = true
+			outcome = outcomeFailed
+			failed++
+		} else {
+			applied++
+			if err := j.record(c); err != nil {
+				return fmt.Errorf("Cannot record change in journal %s: %v", j.path(), err)
+			}
+		}
+		if err := state.set(c.Hostname, outcome); err != nil {
+			return fmt.Errorf("Cannot record outcome in state %s: %v", state.path, err)
+		}
+	}
+	if opts.summary != nil {
+		opts.summary.add(zoneSummary{
+			provider: provider.Name(),
+			zone:     provider.Zone(),
+			changes:  len(changes),
+			applied:  applied,
+			failed:   failed,
+			skipped:  skipped,
+		})
+	}
+	if dryRun || len(changes) == 0 {
+		return nil
+	}
+	fmt.Printf("Applied %d changes, %d failed, %d skipped\n", applied, failed, skipped)
+	if failed > 0 {
+		return &failedChangesError{failed: failed, skipped: skipped, total: len(changes)}
+	}
+	return nil
+}
+
+// resumeChanges loads the state of a previous run and drops the changes of the
+// hostnames that run already completed
+func resumeChanges(provider DNSProvider, changes []change, opts runOptions) ([]change, *runState, error) {
+	state, err := loadState(opts.statePath, opts.backupDir, provider)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Cannot load state: %v", err)
+	}
+	var remaining []change
+	for _, c := range changes {
+		if state.done(c.Hostname) {
+			continue
+		}
+		remaining = append(remaining, c)
+	}
+	fmt.Printf("Resuming from state: %s, skipping %d changes of hostnames already done\n", state.path, len(changes)-len(remaining))
+	return remaining, state, nil
+}
+
+func applyChange(provider DNSProvider, c change) error {
+	switch c.Action {
+	case actionUpdate:
+		return provider.UpdateRecord(*c.Before, c.After.Values)
+	case actionDelete:
+		return provider.DeleteRecord(*c.Before)
+	case actionCreate:
+		return provider.CreateRecord(*c.After)
+	}
+	return fmt.Errorf("unknown action: %s", c.Action)
+}
+
+// applyPlan applies the changes of a plan file to the zone, after verifying
+// that the records of the zone still match the state recorded in the plan.
+func applyPlan(provider DNSProvider, path string, opts runOptions) error {
+	p, err := readPlan(path)
+	if err != nil {
+		return err
+	}
+	if p.Provider != provider.Name() || p.Zone != provider.Zone() {
+		return fmt.Errorf("plan is for %s zone: %s, not %s zone: %s", p.Provider, p.Zone, provider.Name(), provider.Zone())
+	}
+	records, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+	// Changes already applied by a previous run no longer match the records,
+	// drop them before verifying the rest
+	changes := p.Changes
+	if opts.resume {
+		changes, _, err = resumeChanges(provider, changes, opts)
+		if err != nil {
+			return err
+		}
+		opts.resume = false
+	}
+	if err := verifyChanges(changes, records, recordPerValue(provider)); err != nil {
+		return fmt.Errorf("Refusing to apply plan %s: %v", path, err)
+	}
+	return applyChanges(provider, records, changes, opts)
+}
+
+// verifyChanges checks that the records a list of changes was computed from
+// are still found unchanged in the zone, and that records to be created do not
+// exist yet. Changes are verified in order, each one against the state the
+// previous ones leave the records in. perValue is set for providers that store
+// one record per value, where several records can share a name and type.
+func verifyChanges(changes []change, records []Record, perValue bool) error {
+	current := make([]Record, len(records))
+	for i, record := range records {
+		current[i] = copyRecord(record)
+	}
+	var mismatches int
+	for _, c := range changes {
+		switch c.Action {
+		case actionUpdate, actionDelete:
+			i := matchRecord(current, *c.Before, perValue)
+			if i < 0 {
+				log.Printf("Record: %s Type: %s no longer exists", c.Before.Name, c.Before.Type)
+				mismatches++
+				continue
+			}
+			if !recordsEqual(current[i], *c.Before) {
+				log.Printf("Record: %s Type: %s has values: %s, expected: %s", c.Before.Name, c.Before.Type, current[i].Values, c.Before.Values)
+				mismatches++
+				continue
+			}
+			if c.Action == actionUpdate {
+				current[i].Values = c.After.Values
+			} else {
+				current = append(current[:i], current[i+1:]...)
+			}
+		case actionCreate:
+			after := copyRecord(*c.After)
+			after.ID = ""
+			if matchRecord(current, after, perValue) >= 0 {
+				log.Printf("Record: %s Type: %s already exists", c.After.Name, c.After.Type)
+				mismatches++
+				continue
+			}
+			current = append(current, after)
+		default:
+			return fmt.Errorf("unknown action: %s", c.Action)
+		}
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%d records do not match the recorded state", mismatches)
+	}
+	return nil
+}
+
+// recordsEqual returns true if both records have the same name, type, alias
+// target, routing policy and set of values
+func recordsEqual(a, b Record) bool {
+	if sanitizeDNSAddress(a.Name) != sanitizeDNSAddress(b.Name) || a.Type != b.Type {
+		return false
+	}
+	if (a.Alias == nil) != (b.Alias == nil) || (a.Alias != nil && *a.Alias != *b.Alias) {
+		return false
+	}
+	if !reflect.DeepEqual(a.RoutingPolicy, b.RoutingPolicy) {
+		return false
+	}
+	if len(a.Values) != len(b.Values) {
+		return false
+	}
+	av := append([]string(nil), a.Values...)
+	bv := append([]string(nil), b.Values...)
+	sort.Strings(av)
+	sort.Strings(bv)
+	for i := range av {
+		if av[i] != bv[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writePlan(path string, p plan) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+func readPlan(path string) (plan, error) {
+	var p plan
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, fmt.Errorf("failed to read plan: %w", err)
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	return p, nil
+}
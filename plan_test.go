@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// migrationPlan writes the plan of migrating the TXT records of a.example.com
+// from the old to the new owner ID and returns its path
+func migrationPlan(t *testing.T, provider DNSProvider) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plan.json")
+	registry := newTestRegistry(t, "infra", "")
+	hostnames := []kubeHostname{{Hostname: "a.example.com", Resource: "ingress/ns/a"}}
+	migration := ownerMigration{oldOwners: newTestOwnerSelector(t, "old", ""), newOwner: "new"}
+	if err := migrateOwner(provider, hostnames, registry, migration, runOptions{planPath: path}); err != nil {
+		t.Fatalf("migrateOwner() error = %v", err)
+	}
+	return path
+}
+
+func newPlanTestProvider(zone string) *memoryProvider {
+	return newMemoryProvider(zone,
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+		testRecord("infra-a-a.example.com", "TXT", registryValue("old", "ingress/ns/a")),
+	)
+}
+
+func TestApplyPlan(t *testing.T) {
+	provider := newPlanTestProvider("example.com")
+	before := zoneRecords(t, provider)
+	path := migrationPlan(t, provider)
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, before) {
+		t.Fatalf("zone records after writing the plan = %v, want %v", got, before)
+	}
+	p, err := readPlan(path)
+	if err != nil {
+		t.Fatalf("readPlan() error = %v", err)
+	}
+	if p.Provider != "memory" || p.Zone != "example.com" || len(p.Changes) != 2 {
+		t.Fatalf("readPlan() = %s zone: %s with %d changes, want memory zone: example.com with 2 changes", p.Provider, p.Zone, len(p.Changes))
+	}
+
+	if err := applyPlan(provider, path, runOptions{backupDir: t.TempDir()}); err != nil {
+		t.Fatalf("applyPlan() error = %v", err)
+	}
+	want := map[string][]string{
+		"a.example.com. A":           {"1.1.1.1"},
+		"infra-a.example.com. TXT":   {registryValue("new", "ingress/ns/a")},
+		"infra-a-a.example.com. TXT": {registryValue("new", "ingress/ns/a")},
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, want) {
+		t.Errorf("zone records = %v, want %v", got, want)
+	}
+}
+
+func TestApplyPlanRefusesChangedRecords(t *testing.T) {
+	provider := newPlanTestProvider("example.com")
+	path := migrationPlan(t, provider)
+	records, _ := provider.Records()
+	if err := provider.UpdateRecord(records[2], []string{registryValue("other", "ingress/ns/a")}); err != nil {
+		t.Fatalf("UpdateRecord() error = %v", err)
+	}
+	before := zoneRecords(t, provider)
+
+	if err := applyPlan(provider, path, runOptions{backupDir: t.TempDir()}); err == nil {
+		t.Fatal("applyPlan() error = nil, want an error")
+	}
+	if got := zoneRecords(t, provider); !reflect.DeepEqual(got, before) {
+		t.Errorf("zone records = %v, want %v", got, before)
+	}
+}
+
+func TestApplyPlanRefusesOtherZone(t *testing.T) {
+	path := migrationPlan(t, newPlanTestProvider("example.com"))
+	provider := newPlanTestProvider("example.org")
+	if err := applyPlan(provider, path, runOptions{backupDir: t.TempDir()}); err == nil {
+		t.Error("applyPlan() error = nil, want an error")
+	}
+}
+
+func TestGCPProviderZone(t *testing.T) {
+	// Zone names are only unique within a project
+	if got, want := newGCPProvider(nil, "uw-dev", "dev-zone").Zone(), "uw-dev/dev-zone"; got != want {
+		t.Errorf("Zone() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Record is a provider neutral representation of a DNS record set. Names are
+// always fully qualified and end with a dot.
+type Record struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	TTL    int64    `json:"ttl,omitempty"`
+	Values []string `json:"values,omitempty"`
+	// ID is the identifier of the record for providers that address records
+	// by ID (Cloudflare)
+	ID string `json:"id,omitempty"`
+	// Proxied is the Cloudflare proxy status of the record
+	Proxied *bool `json:"proxied,omitempty"`
+	// Alias is the target of a Route53 alias record
+	Alias *AliasTarget `json:"alias,omitempty"`
+	// RoutingPolicy is the routing policy of a Route53 record set
+	RoutingPolicy *RoutingPolicy `json:"routingPolicy,omitempty"`
+}
+
+// setIdentifier returns the Route53 set identifier of the record, if it has
+// a routing policy
+func (r Record) setIdentifier() string {
+	if r.RoutingPolicy == nil {
+		return ""
+	}
+	return r.RoutingPolicy.SetIdentifier
+}
+
+// AliasTarget describes the target of a Route53 alias record.
+type AliasTarget struct {
+	DNSName              string `json:"dnsName"`
+	HostedZoneID         string `json:"hostedZoneID"`
+	EvaluateTargetHealth bool   `json:"evaluateTargetHealth"`
+}
+
+// RoutingPolicy describes the routing policy of a Route53 record set. Record
+// sets with a routing policy share their name and type with others and are
+// told apart by their set identifier.
+type RoutingPolicy struct {
+	SetIdentifier        string                `json:"setIdentifier"`
+	Weight               *int64                `json:"weight,omitempty"`
+	Region               string                `json:"region,omitempty"`
+	Failover             string                `json:"failover,omitempty"`
+	GeoLocation          *GeoLocation          `json:"geoLocation,omitempty"`
+	GeoProximityLocation *GeoProximityLocation `json:"geoProximityLocation,omitempty"`
+	CIDRRoutingConfig    *CIDRRoutingConfig    `json:"cidrRoutingConfig,omitempty"`
+	MultiValueAnswer     *bool                 `json:"multiValueAnswer,omitempty"`
+	HealthCheckID        string                `json:"healthCheckID,omitempty"`
+}
+
+// GeoLocation is the location of a Route53 geolocation record set
+type GeoLocation struct {
+	ContinentCode   string `json:"continentCode,omitempty"`
+	CountryCode     string `json:"countryCode,omitempty"`
+	SubdivisionCode string `json:"subdivisionCode,omitempty"`
+}
+
+// GeoProximityLocation is the location of a Route53 geoproximity record set
+type GeoProximityLocation struct {
+	AWSRegion      string `json:"awsRegion,omitempty"`
+	LocalZoneGroup string `json:"localZoneGroup,omitempty"`
+	Latitude       string `json:"latitude,omitempty"`
+	Longitude      string `json:"longitude,omitempty"`
+	Bias           *int32 `json:"bias,omitempty"`
+}
+
+// CIDRRoutingConfig is the CIDR location of a Route53 IP-based record set
+type CIDRRoutingConfig struct {
+	CollectionID string `json:"collectionID"`
+	LocationName string `json:"locationName"`
+}
+
+// DNSProvider is implemented by all the DNS services that the migrator can
+// manage records in. A DNSProvider is bound to a single zone.
+type DNSProvider interface {
+	// Name returns the name of the provider as passed to the -provider flag
+	Name() string
+	// Zone returns the identifier of the zone the provider manages
+	Zone() string
+	// Records lists all the records found in the zone
+	Records() ([]Record, error)
+	// UpdateRecord replaces the values of an existing record
+	UpdateRecord(record Record, values []string) error
+	// DeleteRecord deletes an existing record
+	DeleteRecord(record Record) error
+	// CreateRecord creates a new record
+	CreateRecord(record Record) error
+}
+
+// newDNSProvider returns a DNSProvider for the named provider, configured from
+// the command line flags.
+func newDNSProvider(name string) (DNSProvider, error) {
+	switch name {
+	case "aws":
+		if *flagAWSZoneID == "" {
+			return nil, fmt.Errorf("-aws-zone-id is required for the aws provider")
+		}
+		return newZoneProvider(name, *flagAWSZoneID)
+	case "cloudflare":
+		if *flagCloudflareZoneName == "" {
+			return nil, fmt.Errorf("-cloudflare-zone-name is required for the cloudflare provider")
+		}
+		return newZoneProvider(name, *flagCloudflareZoneName)
+	case "gcp":
+		if *flagGCPZoneName == "" || *flagGCPProjectID == "" {
+			return nil, fmt.Errorf("-gcp-zone-name and -gcp-project-id are required for the gcp provider")
+		}
+		return newZoneProvider(name, *flagGCPProjectID+"/"+*flagGCPZoneName)
+	case "memory":
+		if *flagMemoryZoneFile == "" {
+			return nil, fmt.Errorf("-memory-zone-file is required for the memory provider")
+		}
+		return newZoneProvider(name, *flagMemoryZoneFile)
+	}
+	return nil, fmt.Errorf("unknown provider: %s", name)
+}
+
+// newZoneProvider returns a DNSProvider for a zone of the named provider. AWS
+// zones are passed by ID, Cloudflare zones by name, GCP zones as
+// <project-id>/<zone-name> and memory zones as the path of the zone file.
+func newZoneProvider(name, zone string) (DNSProvider, error) {
+	switch name {
+	case "aws":
+		return newRoute53Provider(newRoute53Client(), zone), nil
+	case "cloudflare":
+		apiKey := getEnv("CLOUDFLARE_API_KEY", "")
+		email := getEnv("CLOUDFLARE_EMAIL", "")
+		api, err := newCloudflareAPIClient(apiKey, email)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot create Cloudflare API client from key: %v", err)
+		}
+		return newCloudflareProvider(api, zone), nil
+	case "gcp":
+		projectID, zoneName, ok := strings.Cut(zone, "/")
+		if !ok || projectID == "" || zoneName == "" {
+			return nil, fmt.Errorf("gcp zones must be in the form of <project-id>/<zone-name>, got: %s", zone)
+		}
+		service, err := newGCPDNSClient()
+		if err != nil {
+			return nil, fmt.Errorf("Cannot create GCP client: %v", err)
+		}
+		return newGCPProvider(service, projectID, zoneName), nil
+	case "memory":
+		p, err := newMemoryProviderFromFile(zone)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown provider: %s", name)
+}
+
+// parseZoneSpec returns a DNSProvider for a zone passed in the form of
+// <provider>:<zone>, e.g. aws:ZPLLMOCKBH0LL or gcp:my-project/my-zone
+func parseZoneSpec(spec string) (DNSProvider, error) {
+	name, zone, ok := strings.Cut(spec, ":")
+	if !ok || zone == "" {
+		return nil, fmt.Errorf("zones must be in the form of <provider>:<zone>, got: %s", spec)
+	}
+	return newZoneProvider(name, zone)
+}
+
+// findRecord returns the index of the record in records, or -1 if it is not
+// found. Records are matched by ID when they have one and by name and type
+// otherwise. Records with a Route53 set identifier only match the record set
+// of the same identifier.
+func findRecord(records []Record, record Record) int {
+	for i, r := range records {
+		if record.ID != "" {
+			if r.ID == record.ID {
+				return i
+			}
+			continue
+		}
+		if r.Name != sanitizeDNSAddress(record.Name) || r.Type != record.Type {
+			continue
+		}
+		if record.setIdentifier() == "" || r.setIdentifier() == record.setIdentifier() {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchRecord returns the index of the record in records that stands for the
+// passed one, or -1 if there is none. Providers that store one record per value
+// can hold several records of the same name and type, those are matched by ID
+// while it is still present and by their values otherwise.
+func matchRecord(records []Record, record Record, perValue bool) int {
+	if !perValue {
+		record.ID = ""
+		return findRecord(records, record)
+	}
+	if record.ID != "" {
+		if i := findRecord(records, record); i >= 0 {
+			return i
+		}
+	}
+	for i, r := range records {
+		if recordsEqual(r, record) {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyRecord returns a deep copy of the record
+func copyRecord(record Record) Record {
+	c := record
+	c.Values = append([]string(nil), record.Values...)
+	if record.Proxied != nil {
+		proxied := *record.Proxied
+		c.Proxied = &proxied
+	}
+	if record.Alias != nil {
+		alias := *record.Alias
+		c.Alias = &alias
+	}
+	if record.RoutingPolicy != nil {
+		c.RoutingPolicy = record.RoutingPolicy.copy()
+	}
+	return c
+}
+
+// copy returns a deep copy of the routing policy
+func (p *RoutingPolicy) copy() *RoutingPolicy {
+	c := *p
+	if p.Weight != nil {
+		weight := *p.Weight
+		c.Weight = &weight
+	}
+	if p.GeoLocation != nil {
+		location := *p.GeoLocation
+		c.GeoLocation = &location
+	}
+	if p.GeoProximityLocation != nil {
+		location := *p.GeoProximityLocation
+		if location.Bias != nil {
+			bias := *location.Bias
+			location.Bias = &bias
+		}
+		c.GeoProximityLocation = &location
+	}
+	if p.CIDRRoutingConfig != nil {
+		config := *p.CIDRRoutingConfig
+		c.CIDRRoutingConfig = &config
+	}
+	if p.MultiValueAnswer != nil {
+		multiValueAnswer := *p.MultiValueAnswer
+		c.MultiValueAnswer = &multiValueAnswer
+	}
+	return &c
+}
+
+// quotedTXT returns true if the provider expects TXT record values to be
+// quoted. Cloudflare is the only provider that stores them unquoted.
+func quotedTXT(provider DNSProvider) bool {
+	return provider.Name() != "cloudflare"
+}
+
+// recordPerValue returns true if the provider stores one record per value
+// instead of one record set per name and type, like Cloudflare does.
+func recordPerValue(provider DNSProvider) bool {
+	return provider.Name() == "cloudflare"
+}
+
+// zoneDomain returns the domain of the zone: the name of Cloudflare zones and
+// the name of the SOA record for other providers. An empty domain is returned
+// if it cannot be found.
+func zoneDomain(provider DNSProvider, records []Record) string {
+	if provider.Name() == "cloudflare" {
+		return sanitizeDNSAddress(provider.Zone())
+	}
+	for _, record := range records {
+		if record.Type == "SOA" {
+			return record.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+// withSetIdentifier returns the record with a weighted Route53 routing policy
+// of the set identifier
+func withSetIdentifier(record Record, setIdentifier string) Record {
+	weight := int64(1)
+	record.RoutingPolicy = &RoutingPolicy{SetIdentifier: setIdentifier, Weight: &weight}
+	return record
+}
+
+func TestFindRecord(t *testing.T) {
+	blue := withSetIdentifier(testRecord("a.example.com", "A", "1.1.1.1"), "blue")
+	green := withSetIdentifier(testRecord("a.example.com", "A", "2.2.2.2"), "green")
+	records := []Record{
+		withID(testRecord("b.example.com", "A", "3.3.3.3"), "1"),
+		blue,
+		green,
+	}
+	tests := []struct {
+		name   string
+		record Record
+		want   int
+	}{
+		{name: "by name and type", record: Record{Name: "b.example.com", Type: "A"}, want: 0},
+		{name: "by ID", record: withID(Record{Name: "c.example.com", Type: "A"}, "1"), want: 0},
+		{name: "missing ID", record: withID(Record{Name: "b.example.com", Type: "A"}, "2"), want: -1},
+		{name: "other type", record: Record{Name: "b.example.com", Type: "AAAA"}, want: -1},
+		{name: "by set identifier", record: green, want: 2},
+		{name: "missing set identifier", record: Record{Name: "a.example.com", Type: "A", RoutingPolicy: &RoutingPolicy{SetIdentifier: "red"}}, want: -1},
+		{name: "any set identifier", record: Record{Name: "a.example.com", Type: "A"}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findRecord(records, tt.record); got != tt.want {
+				t.Errorf("findRecord() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
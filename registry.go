@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// recordTypeTemplate is replaced by the lower case record type in TXT prefixes
+// and suffixes, like external-dns does
+const recordTypeTemplate = "%{record_type}"
+
+// txtRegistry describes how external-dns names and encodes the TXT records
+// that hold the ownership information of a hostname, as configured with its
+// --txt-prefix, --txt-suffix and --txt-encrypt-aes-key flags.
+type txtRegistry struct {
+	prefix string
+	suffix string
+	// aesKey decrypts encrypted TXT record values
+	aesKey []byte
+	// newAESKey encrypts the rewritten values of encrypted TXT records
+	newAESKey []byte
+}
+
+// newTXTRegistry returns a txtRegistry for the passed prefix or suffix. Both
+// accept the %{record_type} template of external-dns and are used as they are,
+// like external-dns does. For backwards compatibility a plain prefix that does
+// not end with a dash or a dot is followed by a dash.
+// If an AES key is passed, encrypted TXT record values are decrypted with it
+// and re-encrypted with the new AES key, or the same one if no new key is
+// passed, when rewritten.
+func newTXTRegistry(prefix, suffix, aesKey, newAESKey string) (txtRegistry, error) {
+	if prefix != "" && suffix != "" {
+		return txtRegistry{}, fmt.Errorf("a TXT prefix and suffix cannot be used together")
+	}
+	if prefix != "" && !strings.Contains(prefix, recordTypeTemplate) && !strings.HasSuffix(prefix, "-") && !strings.HasSuffix(prefix, ".") {
+		prefix += "-"
+	}
+	r := txtRegistry{
+		prefix: prefix,
+		suffix: suffix,
+	}
+	if aesKey != "" {
+		key, err := parseAESKey(aesKey)
+		if err != nil {
+			return txtRegistry{}, fmt.Errorf("invalid TXT encryption key: %v", err)
+		}
+		r.aesKey = key
+		r.newAESKey = key
+	}
+	if newAESKey != "" {
+		if aesKey == "" {
+			return txtRegistry{}, fmt.Errorf("a new TXT encryption key requires the current key")
+		}
+		key, err := parseAESKey(newAESKey)
+		if err != nil {
+			return txtRegistry{}, fmt.Errorf("invalid new TXT encryption key: %v", err)
+		}
+		r.newAESKey = key
+	}
+	return r, nil
+}
+
+// isSet returns true if either a prefix or a suffix is configured
+func (r txtRegistry) isSet() bool {
+	return r.prefix != "" || r.suffix != ""
+}
+
+// txtNames returns the names of the TXT records that can hold the ownership
+// information of a hostname with a record of the passed type. These are the
+// legacy name, that does not include the record type, and the name that
+// includes it. The prefix is added in front of the hostname and the suffix is
+// inserted before its first label.
+func (r txtRegistry) txtNames(hostname, recordType string) []string {
+	return []string{
+		r.legacyTXTName(hostname),
+		r.typedTXTName(hostname, recordType),
+	}
+}
+
+// legacyTXTName returns the TXT record name external-dns used before record
+// types were part of it. Record type templates are dropped from the affixes.
+func (r txtRegistry) legacyTXTName(hostname string) string {
+	prefix := strings.ReplaceAll(r.prefix, recordTypeTemplate, "")
+	suffix := strings.ReplaceAll(r.suffix, recordTypeTemplate, "")
+	return affixDNSName(hostname, prefix, suffix)
+}
+
+// typedTXTName returns the TXT record name that includes the record type.
+// The type is either placed in the affix template or in front of the first
+// label of the hostname.
+func (r txtRegistry) typedTXTName(hostname, recordType string) string {
+	recordType = strings.ToLower(recordType)
+	if strings.Contains(r.prefix, recordTypeTemplate) || strings.Contains(r.suffix, recordTypeTemplate) {
+		prefix := strings.ReplaceAll(r.prefix, recordTypeTemplate, recordType)
+		suffix := strings.ReplaceAll(r.suffix, recordTypeTemplate, recordType)
+		return affixDNSName(hostname, prefix, suffix)
+	}
+	return affixDNSName(hostname, r.prefix+recordType+"-", r.suffix)
+}
+
+// affixDNSName adds the prefix in front of a hostname and inserts the suffix
+// after its first label
+func affixDNSName(hostname, prefix, suffix string) string {
+	labels := strings.SplitN(sanitizeDNSAddress(hostname), ".", 2)
+	return prefix + labels[0] + suffix + "." + labels[1]
+}
+
+// parseValue returns the labels of a TXT record value, together with the
+// format the value is stored in. Values encrypted with the registry AES key are
+// decrypted, and values that cannot be decrypted are parsed as they are, like
+// external-dns does. An error is returned if the value is not an external-dns
+// registry entry.
+func (r txtRegistry) parseValue(value string) (labels, txtFormat, error) {
+	text, quoted := unquoteTXT(value)
+	format := txtFormat{quoted: quoted}
+	if len(r.aesKey) > 0 {
+		if plainText, err := decryptText(text, r.aesKey); err == nil {
+			text = plainText
+			format.encrypted = true
+		}
+	}
+	l, err := parseLabels(text)
+	return l, format, err
+}
+
+// formatValue serializes the labels to a TXT record value of the passed
+// format. Encrypted values are encrypted with the new AES key and a fresh
+// nonce.
+func (r txtRegistry) formatValue(l labels, format txtFormat) (string, error) {
+	text := l.String()
+	if format.encrypted {
+		var err error
+		text, err = encryptText(text, r.newAESKey)
+		if err != nil {
+			return "", err
+		}
+	}
+	if format.quoted {
+		return quoteTXT(text), nil
+	}
+	return text, nil
+}
+
+// owner returns the owner of a TXT record value, if it is an external-dns
+// registry entry
+func (r txtRegistry) owner(value string) (string, bool) {
+	l, _, err := r.parseValue(value)
+	if err != nil {
+		return "", false
+	}
+	owner, ok := l[labelOwner]
+	return owner, ok
+}
+
+// verifyOwner returns true if the TXT record value is owned by owner
+func (r txtRegistry) verifyOwner(value, owner string) bool {
+	o, ok := r.owner(value)
+	return ok && o == owner
+}
+
+// rewriteLabels applies the rewrite function to the labels of a TXT record
+// value and returns the value in the same format. The returned bool is false if
+// the labels were left unchanged.
+func (r txtRegistry) rewriteLabels(value string, rewrite func(labels)) (string, bool, error) {
+	l, format, err := r.parseValue(value)
+	if err != nil {
+		return "", false, err
+	}
+	rewritten := l.copy()
+	rewrite(rewritten)
+	if rewritten.equal(l) {
+		return value, false, nil
+	}
+	v, err := r.formatValue(rewritten, format)
+	return v, true, err
+}
+
+// hasHeritage returns true if the TXT record value is an external-dns
+// registry entry
+func (r txtRegistry) hasHeritage(value string) bool {
+	_, _, err := r.parseValue(value)
+	return err == nil
+}
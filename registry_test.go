@@ -0,0 +1,128 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTXTNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		suffix     string
+		hostname   string
+		recordType string
+		want       []string
+	}{
+		{
+			name:       "no prefix or suffix",
+			hostname:   "a.example.com",
+			recordType: "A",
+			want:       []string{"a.example.com.", "a-a.example.com."},
+		},
+		{
+			name:       "prefix",
+			prefix:     "infra",
+			hostname:   "a.example.com",
+			recordType: "A",
+			want:       []string{"infra-a.example.com.", "infra-a-a.example.com."},
+		},
+		{
+			name:       "prefix ending with a dash",
+			prefix:     "infra-",
+			hostname:   "a.b.example.com.",
+			recordType: "CNAME",
+			want:       []string{"infra-a.b.example.com.", "infra-cname-a.b.example.com."},
+		},
+		{
+			name:       "suffix",
+			suffix:     "infra",
+			hostname:   "a.example.com",
+			recordType: "A",
+			want:       []string{"ainfra.example.com.", "a-ainfra.example.com."},
+		},
+		{
+			name:       "suffix starting with an underscore",
+			suffix:     "_owner",
+			hostname:   "app.example.com",
+			recordType: "A",
+			want:       []string{"app_owner.example.com.", "a-app_owner.example.com."},
+		},
+		{
+			name:       "suffix starting with a dash",
+			suffix:     "-infra",
+			hostname:   "a.example.com",
+			recordType: "AAAA",
+			want:       []string{"a-infra.example.com.", "aaaa-a-infra.example.com."},
+		},
+		{
+			name:       "prefix with a record type template",
+			prefix:     "%{record_type}-infra-",
+			hostname:   "a.example.com",
+			recordType: "CNAME",
+			want:       []string{"-infra-a.example.com.", "cname-infra-a.example.com."},
+		},
+		{
+			name:       "prefix with a dot separator",
+			prefix:     "infra.",
+			hostname:   "a.example.com",
+			recordType: "A",
+			want:       []string{"infra.a.example.com.", "infra.a-a.example.com."},
+		},
+		{
+			name:       "prefix with a record type template and a dot separator",
+			prefix:     "infra-%{record_type}.",
+			hostname:   "a.example.com",
+			recordType: "A",
+			want:       []string{"infra-.a.example.com.", "infra-a.a.example.com."},
+		},
+		{
+			name:       "suffix with a record type template",
+			suffix:     "-%{record_type}-infra",
+			hostname:   "a.example.com",
+			recordType: "AAAA",
+			want:       []string{"a--infra.example.com.", "a-aaaa-infra.example.com."},
+		},
+		{
+			name:       "suffix with a dot separator",
+			suffix:     ".infra",
+			hostname:   "a.example.com",
+			recordType: "A",
+			want:       []string{"a.infra.example.com.", "a-a.infra.example.com."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newTestRegistry(t, tt.prefix, tt.suffix)
+			if got := registry.txtNames(tt.hostname, tt.recordType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("txtNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTXTRegistryPrefixAndSuffix(t *testing.T) {
+	if _, err := newTXTRegistry("infra", "infra", "", ""); err == nil {
+		t.Error("newTXTRegistry() error = nil, want an error")
+	}
+}
+
+func TestTXTRegistryOwner(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	tests := []struct {
+		value     string
+		wantOwner string
+		wantOK    bool
+	}{
+		{value: `"heritage=external-dns,external-dns/owner=infra,external-dns/resource=ingress/ns/a"`, wantOwner: "infra", wantOK: true},
+		{value: "heritage=external-dns,external-dns/owner=infra", wantOwner: "infra", wantOK: true},
+		{value: `"heritage=external-dns,external-dns/resource=ingress/ns/a"`},
+		{value: `"v=spf1 -all"`},
+	}
+	for _, tt := range tests {
+		owner, ok := registry.owner(tt.value)
+		if owner != tt.wantOwner || ok != tt.wantOK {
+			t.Errorf("owner(%s) = %s, %v, want %s, %v", tt.value, owner, ok, tt.wantOwner, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+)
+
+// rotateEncryptionKey re-encrypts the values of all the external-DNS TXT
+// records of a zone that are encrypted with the registry AES key, using the
+// new AES key. The owner and the rest of the registry text are left as they
+// are.
+func rotateEncryptionKey(provider DNSProvider, registry txtRegistry, opts runOptions) error {
+	records, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+	changes, err := planKeyRotation(records, registry)
+	if err != nil {
+		return err
+	}
+	return executeChanges(provider, records, changes, opts)
+}
+
+// planKeyRotation returns the changes needed to re-encrypt the encrypted
+// external-DNS TXT records with the new AES key
+func planKeyRotation(records []Record, registry txtRegistry) ([]change, error) {
+	var changes []change
+	for _, record := range records {
+		if record.Type != "TXT" {
+			continue
+		}
+		rotated := false
+		newValues := make([]string, len(record.Values))
+		for i, value := range record.Values {
+			newValues[i] = value
+			l, format, err := registry.parseValue(value)
+			if err != nil || !format.encrypted {
+				continue
+			}
+			v, err := registry.formatValue(l, format)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot encrypt record: %s, %v", record.Name, err)
+			}
+			newValues[i] = v
+			rotated = true
+		}
+		if rotated {
+			changes = append(changes, updateChange(record.Name, record, newValues))
+		}
+	}
+	return changes, nil
+}
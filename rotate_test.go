@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlanKeyRotation(t *testing.T) {
+	registry, err := newTXTRegistry("infra", "", testAESKey, testNewAESKey)
+	if err != nil {
+		t.Fatalf("newTXTRegistry() error = %v", err)
+	}
+	// Values are encrypted with the current key
+	text, err := encryptText(labels{labelOwner: "infra"}.String(), []byte(testAESKey))
+	if err != nil {
+		t.Fatalf("encryptText() error = %v", err)
+	}
+	encrypted := quoteTXT(text)
+	records := []Record{
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", encrypted, `"v=spf1 -all"`),
+		testRecord("infra-b.example.com", "TXT", registryValue("infra", "")),
+	}
+
+	changes, err := planKeyRotation(records, registry)
+	if err != nil {
+		t.Fatalf("planKeyRotation() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Before.Name != "infra-a.example.com." {
+		t.Fatalf("planKeyRotation() = %q, want an update of infra-a.example.com.", changeList(changes))
+	}
+	values := changes[0].After.Values
+	if len(values) != 2 || values[1] != `"v=spf1 -all"` {
+		t.Fatalf("planKeyRotation() values = %q, want the rotated value and the SPF value", values)
+	}
+	rotated, err := decryptText(mustUnquoteTXT(values[0]), []byte(testNewAESKey))
+	if err != nil {
+		t.Fatalf("rotated value cannot be decrypted with the new key: %v", err)
+	}
+	if want := (labels{labelOwner: "infra"}).String(); rotated != want {
+		t.Errorf("rotated value = %s, want %s", rotated, want)
+	}
+}
+
+func mustUnquoteTXT(value string) string {
+	text, _ := unquoteTXT(value)
+	return text
+}
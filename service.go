@@ -9,8 +9,8 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func externalDNSServiceHostnames(clientset *kubernetes.Clientset) ([]string, error) {
-	var hostnames []string
+func externalDNSServiceHostnames(clientset kubernetes.Interface) ([]kubeHostname, error) {
+	var hostnames []kubeHostname
 	// Regex to match annotations like external-dns.alpha.kubernetes.io/.*=<value>
 	re := regexp.MustCompile(`^external-dns\.alpha\.kubernetes\.io/.*$`)
 
@@ -22,7 +22,12 @@ func externalDNSServiceHostnames(clientset *kubernetes.Clientset) ([]string, err
 	for _, svc := range services.Items {
 		for key, value := range svc.Annotations {
 			if re.MatchString(key) {
-				hostnames = append(hostnames, value)
+				hostnames = append(hostnames, kubeHostname{
+					Hostname:  value,
+					Resource:  kubeResource("service", svc.Namespace, svc.Name),
+					Namespace: svc.Namespace,
+					Labels:    svc.Labels,
+				})
 			}
 		}
 	}
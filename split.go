@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+
+	kubelabels "k8s.io/apimachinery/pkg/labels"
+)
+
+// splitRule selects the hostnames of the cluster that move to a new owner ID
+// when an owner is split. All the set selectors of a rule must match.
+type splitRule struct {
+	Owner         string   `json:"owner"`
+	Namespaces    []string `json:"namespaces,omitempty"`
+	IngressClass  string   `json:"ingressClass,omitempty"`
+	LabelSelector string   `json:"labelSelector,omitempty"`
+	HostnameRegex string   `json:"hostnameRegex,omitempty"`
+
+	selector kubelabels.Selector
+	hostname *regexp.Regexp
+}
+
+// readSplitRules reads a JSON list of split rules from path
+func readSplitRules(path string) ([]splitRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read split rules: %w", err)
+	}
+	var rules []splitRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse split rules %s: %w", path, err)
+	}
+	for i := range rules {
+		if rules[i].Owner == "" {
+			return nil, fmt.Errorf("split rule %d has no owner", i)
+		}
+		if rules[i].LabelSelector != "" {
+			rules[i].selector, err = kubelabels.Parse(rules[i].LabelSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid label selector of split rule %d: %w", i, err)
+			}
+		}
+		if rules[i].HostnameRegex != "" {
+			rules[i].hostname, err = regexp.Compile(rules[i].HostnameRegex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hostname regex of split rule %d: %w", i, err)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// matches returns true if the hostname is selected by the rule
+func (r splitRule) matches(h kubeHostname) bool {
+	if len(r.Namespaces) > 0 && !slices.Contains(r.Namespaces, h.Namespace) {
+		return false
+	}
+	if r.IngressClass != "" && r.IngressClass != h.IngressClass {
+		return false
+	}
+	if r.selector != nil && !r.selector.Matches(kubelabels.Set(h.Labels)) {
+		return false
+	}
+	if r.hostname != nil && !r.hostname.MatchString(h.Hostname) {
+		return false
+	}
+	return true
+}
+
+// splitOwners maps every hostname to the owner of the first rule that
+// selects it. Hostnames declared by objects that are selected by rules of
+// different owners map to an empty owner, and hostnames not selected by any
+// rule are left out.
+func splitOwners(hostnames []kubeHostname, rules []splitRule) map[string]string {
+	owners := map[string]string{}
+	for _, h := range hostnames {
+		name := sanitizeDNSAddress(h.Hostname)
+		for _, rule := range rules {
+			if !rule.matches(h) {
+				continue
+			}
+			owner, ok := owners[name]
+			if ok && owner == "" {
+				break
+			}
+			if ok && owner != rule.Owner {
+				fmt.Printf("Skipping hostname: %s, selected for owners %s and %s\n", h.Hostname, owner, rule.Owner)
+				owners[name] = ""
+				break
+			}
+			owners[name] = rule.Owner
+			break
+		}
+	}
+	return owners
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testSplitRules writes the JSON split rules to a file and reads them back
+func testSplitRules(t *testing.T, data string) []splitRule {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write split rules: %v", err)
+	}
+	rules, err := readSplitRules(path)
+	if err != nil {
+		t.Fatalf("readSplitRules() error = %v", err)
+	}
+	return rules
+}
+
+func TestReadSplitRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "no owner", data: `[{"namespaces": ["ns"]}]`},
+		{name: "invalid label selector", data: `[{"owner": "a", "labelSelector": "app in (a"}]`},
+		{name: "invalid hostname regex", data: `[{"owner": "a", "hostnameRegex": "("}]`},
+		{name: "invalid JSON", data: `{"owner": "a"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatalf("failed to write split rules: %v", err)
+			}
+			if _, err := readSplitRules(path); err == nil {
+				t.Error("readSplitRules() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestSplitOwners(t *testing.T) {
+	rules := testSplitRules(t, `[
+		{"owner": "team-a", "namespaces": ["team-a"]},
+		{"owner": "internal", "ingressClass": "internal"},
+		{"owner": "team-b", "labelSelector": "team=b"},
+		{"owner": "api", "hostnameRegex": "^api\\."}
+	]`)
+	tests := []struct {
+		name      string
+		hostnames []kubeHostname
+		want      map[string]string
+	}{
+		{
+			name:      "namespace",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Namespace: "team-a"}},
+			want:      map[string]string{"a.example.com.": "team-a"},
+		},
+		{
+			name:      "ingress class",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Namespace: "ns", IngressClass: "internal"}},
+			want:      map[string]string{"a.example.com.": "internal"},
+		},
+		{
+			name:      "label selector",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Namespace: "ns", Labels: map[string]string{"team": "b"}}},
+			want:      map[string]string{"a.example.com.": "team-b"},
+		},
+		{
+			name:      "hostname regex",
+			hostnames: []kubeHostname{{Hostname: "api.example.com", Namespace: "ns"}},
+			want:      map[string]string{"api.example.com.": "api"},
+		},
+		{
+			name:      "first matching rule",
+			hostnames: []kubeHostname{{Hostname: "api.example.com", Namespace: "team-a"}},
+			want:      map[string]string{"api.example.com.": "team-a"},
+		},
+		{
+			name:      "no matching rule",
+			hostnames: []kubeHostname{{Hostname: "a.example.com", Namespace: "ns"}},
+			want:      map[string]string{},
+		},
+		{
+			name: "objects selected for different owners",
+			hostnames: []kubeHostname{
+				{Hostname: "a.example.com", Namespace: "team-a"},
+				{Hostname: "a.example.com", Namespace: "ns", IngressClass: "internal"},
+				{Hostname: "a.example.com", Namespace: "ns", Labels: map[string]string{"team": "b"}},
+			},
+			want: map[string]string{"a.example.com.": ""},
+		},
+		{
+			name: "objects selected for the same owner",
+			hostnames: []kubeHostname{
+				{Hostname: "a.example.com", Namespace: "team-a"},
+				{Hostname: "a.example.com.", Namespace: "team-a"},
+			},
+			want: map[string]string{"a.example.com.": "team-a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitOwners(tt.hostnames, rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitOwners() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanMigrationSplit(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	records := []Record{
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "ingress/team-a/a")),
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("old", "ingress/ns/b")),
+	}
+	hostnames := []kubeHostname{
+		{Hostname: "a.example.com", Resource: "ingress/team-a/a", Namespace: "team-a"},
+		{Hostname: "b.example.com", Resource: "ingress/ns/b", Namespace: "ns"},
+	}
+	migration := ownerMigration{
+		oldOwners:  newTestOwnerSelector(t, "old", ""),
+		splitRules: testSplitRules(t, `[{"owner": "team-a", "namespaces": ["team-a"]}]`),
+	}
+	changes, err := planMigration(hostnames, records, registry, migration)
+	if err != nil {
+		t.Fatalf("planMigration() error = %v", err)
+	}
+	// Hostnames not selected by any rule keep their owner
+	want := []string{"update infra-a.example.com. TXT " + registryValue("team-a", "ingress/team-a/a")}
+	if got := changeList(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("planMigration() = %q, want %q", got, want)
+	}
+}
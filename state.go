@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	outcomeDone       = "done"
+	outcomeFailed     = "failed"
+	outcomeInProgress = "in-progress"
+)
+
+// runState is the checkpoint of a run. It records the outcome of the changes
+// of every processed hostname, so that a failed run can be resumed.
+type runState struct {
+	Provider  string            `json:"provider"`
+	Zone      string            `json:"zone"`
+	Hostnames map[string]string `json:"hostnames"`
+
+	path string
+}
+
+// loadState reads the state file at path. If path is empty a new state file
+// is named in dir, and if the file does not exist an empty state is returned.
+func loadState(path, dir string, provider DNSProvider) (*runState, error) {
+	s := &runState{
+		Provider:  provider.Name(),
+		Zone:      provider.Zone(),
+		Hostnames: map[string]string{},
+		path:      path,
+	}
+	if path == "" {
+		f, err := createBackupFile(dir, provider, time.Now().UTC(), "state.json")
+		if err != nil {
+			return nil, err
+		}
+		s.path = f.Name()
+		return s, f.Close()
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", path, err)
+	}
+	if s.Provider != provider.Name() || s.Zone != provider.Zone() {
+		return nil, fmt.Errorf("state is for %s zone: %s, not %s zone: %s", s.Provider, s.Zone, provider.Name(), provider.Zone())
+	}
+	if s.Hostnames == nil {
+		s.Hostnames = map[string]string{}
+	}
+	return s, nil
+}
+
+// done returns true if all the changes of the hostname were applied by a
+// previous run
+func (s *runState) done(hostname string) bool {
+	return s.Hostnames[sanitizeDNSAddress(hostname)] == outcomeDone
+}
+
+// set records the outcome of the hostname changes and writes the state file
+func (s *runState) set(hostname, outcome string) error {
+	s.Hostnames[sanitizeDNSAddress(hostname)] = outcome
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	// Write to a temporary file first, so that the state is never left
+	// truncated
+	tmp := filepath.Join(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// failedChangesError is returned when some of the changes of a run could not
+// be applied
+type failedChangesError struct {
+	failed  int
+	skipped int
+	total   int
+}
+
+func (e *failedChangesError) Error() string {
+	return fmt.Sprintf("%d of %d changes failed and %d were skipped", e.failed, e.total, e.skipped)
+}
+
+// zoneSummary is the outcome of the changes of a zone
+type zoneSummary struct {
+	provider string
+	zone     string
+	changes  int
+	applied  int
+	failed   int
+	skipped  int
+	err      error
+}
+
+// runSummary collects the outcome of the changes of every zone of a run
+// against multiple zones
+type runSummary struct {
+	zones []zoneSummary
+}
+
+func (s *runSummary) add(z zoneSummary) {
+	s.zones = append(s.zones, z)
+}
+
+// print prints the outcome of every zone and the totals of the run
+func (s *runSummary) print() {
+	var total zoneSummary
+	fmt.Printf("Summary of %d zones:\n", len(s.zones))
+	for _, z := range s.zones {
+		if z.err != nil {
+			fmt.Printf("  %s zone: %s, error: %v\n", z.provider, z.zone, z.err)
+			continue
+		}
+		fmt.Printf("  %s zone: %s, %d changes, %d applied, %d failed, %d skipped\n", z.provider, z.zone, z.changes, z.applied, z.failed, z.skipped)
+		total.changes += z.changes
+		total.applied += z.applied
+		total.failed += z.failed
+		total.skipped += z.skipped
+	}
+	fmt.Printf("Total: %d changes, %d applied, %d failed, %d skipped\n", total.changes, total.applied, total.failed, total.skipped)
+}
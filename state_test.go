@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// failingProvider fails to delete the records with the passed name, after
+// calling onDelete if set
+type failingProvider struct {
+	*memoryProvider
+	failName string
+	onDelete func(record Record)
+}
+
+func (p *failingProvider) DeleteRecord(record Record) error {
+	if p.onDelete != nil {
+		p.onDelete(record)
+	}
+	if record.Name == p.failName {
+		return errors.New("request failed")
+	}
+	return p.memoryProvider.DeleteRecord(record)
+}
+
+func newStateTestProvider() *memoryProvider {
+	return newMemoryProvider("example.com",
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("old", "")),
+		testRecord("c.example.com", "A", "3.3.3.3"),
+		testRecord("infra-c.example.com", "TXT", registryValue("old", "")),
+	)
+}
+
+// deleteChanges returns the changes deleting the records of the hostnames
+func deleteChanges(t *testing.T, provider DNSProvider, hostnames ...string) []change {
+	t.Helper()
+	records, err := provider.Records()
+	if err != nil {
+		t.Fatalf("failed to list records: %v", err)
+	}
+	var changes []change
+	for _, hostname := range hostnames {
+		for _, record := range records {
+			if record.Name == sanitizeDNSAddress(hostname) || record.Name == sanitizeDNSAddress("infra-"+hostname) {
+				changes = append(changes, deleteChange(hostname, record))
+			}
+		}
+	}
+	return changes
+}
+
+func readTestState(t *testing.T, path string, provider DNSProvider) map[string]string {
+	t.Helper()
+	state, err := loadState(path, "", provider)
+	if err != nil {
+		t.Fatalf("loadState() error = %v", err)
+	}
+	return state.Hostnames
+}
+
+func TestApplyChangesRecordsHostnameOutcomes(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	provider := &failingProvider{memoryProvider: newStateTestProvider(), failName: "infra-c.example.com."}
+	// The hostname is not done before its last change is applied
+	var inProgress map[string]string
+	provider.onDelete = func(record Record) {
+		if record.Name == "infra-c.example.com." {
+			inProgress = readTestState(t, statePath, provider)
+		}
+	}
+	records, _ := provider.Records()
+	changes := deleteChanges(t, provider, "a.example.com", "c.example.com")
+
+	err := applyChanges(provider, records, changes, runOptions{backupDir: dir, statePath: statePath})
+	var failedErr *failedChangesError
+	if !errors.As(err, &failedErr) {
+		t.Fatalf("applyChanges() error = %v, want a failedChangesError", err)
+	}
+	if want := map[string]string{"a.example.com.": outcomeDone, "c.example.com.": outcomeInProgress}; !reflect.DeepEqual(inProgress, want) {
+		t.Errorf("state before the last change = %v, want %v", inProgress, want)
+	}
+	if got, want := readTestState(t, statePath, provider), map[string]string{"a.example.com.": outcomeDone, "c.example.com.": outcomeFailed}; !reflect.DeepEqual(got, want) {
+		t.Errorf("state = %v, want %v", got, want)
+	}
+}
+
+func TestApplyChangesResume(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	provider := &failingProvider{memoryProvider: newStateTestProvider(), failName: "c.example.com."}
+	records, _ := provider.Records()
+	changes := deleteChanges(t, provider, "a.example.com", "c.example.com")
+	if err := applyChanges(provider, records, changes, runOptions{backupDir: dir, statePath: statePath}); err == nil {
+		t.Fatal("applyChanges() error = nil, want an error")
+	}
+
+	// Resuming with the changes planned by the failed run skips the hostnames
+	// that are done
+	provider.failName = ""
+	records, _ = provider.Records()
+	if err := applyChanges(provider, records, changes, runOptions{backupDir: dir, statePath: statePath, resume: true}); err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+	if got := zoneRecords(t, provider); len(got) != 0 {
+		t.Errorf("zone records = %v, want none", got)
+	}
+}
+
+func TestApplyPlanResume(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	planPath := filepath.Join(dir, "plan.json")
+	provider := &failingProvider{memoryProvider: newStateTestProvider(), failName: "c.example.com."}
+	p := plan{
+		Provider: provider.Name(),
+		Zone:     provider.Zone(),
+		Changes:  deleteChanges(t, provider, "a.example.com", "c.example.com"),
+	}
+	if err := writePlan(planPath, p); err != nil {
+		t.Fatalf("writePlan() error = %v", err)
+	}
+	if err := applyPlan(provider, planPath, runOptions{backupDir: dir, statePath: statePath}); err == nil {
+		t.Fatal("applyPlan() error = nil, want an error")
+	}
+
+	// The changes of the hostnames that are done no longer match the zone
+	provider.failName = ""
+	if err := applyPlan(provider, planPath, runOptions{backupDir: dir, statePath: statePath}); err == nil {
+		t.Error("applyPlan() error = nil without -resume, want an error")
+	}
+	if err := applyPlan(provider, planPath, runOptions{backupDir: dir, statePath: statePath, resume: true}); err != nil {
+		t.Fatalf("applyPlan() error = %v", err)
+	}
+	if got := zoneRecords(t, provider); len(got) != 0 {
+		t.Errorf("zone records = %v, want none", got)
+	}
+	if got, want := readTestState(t, statePath, provider), map[string]string{"a.example.com.": outcomeDone, "c.example.com.": outcomeDone}; !reflect.DeepEqual(got, want) {
+		t.Errorf("state = %v, want %v", got, want)
+	}
+}
+
+func TestLoadStateOfAnotherZone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := loadState(path, "", newMemoryProvider("example.com"))
+	if err != nil {
+		t.Fatalf("loadState() error = %v", err)
+	}
+	if err := state.set("a.example.com", outcomeDone); err != nil {
+		t.Fatalf("set() error = %v", err)
+	}
+	if _, err := loadState(path, "", newMemoryProvider("example.org")); err == nil {
+		t.Error("loadState() error = nil, want an error")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// migrateTXTFormat creates the TXT records that include the record type, in
+// the format newer external-dns versions use, for all the records owned by the
+// owner ID that only have a legacy TXT record. The legacy TXT records are
+// deleted if removeLegacy is true.
+func migrateTXTFormat(provider DNSProvider, registry txtRegistry, owner string, removeLegacy bool, opts runOptions) error {
+	records, err := provider.Records()
+	if err != nil {
+		return fmt.Errorf("Cannot list records in %s zone: %s, %v", provider.Name(), provider.Zone(), err)
+	}
+	return executeChanges(provider, records, planTXTFormatMigration(records, registry, owner, removeLegacy), opts)
+}
+
+// planTXTFormatMigration returns the changes needed to create the typed TXT
+// records of the records owned by the owner ID, copying the values of their
+// legacy TXT records, and optionally to delete the legacy ones.
+func planTXTFormatMigration(records []Record, registry txtRegistry, owner string, removeLegacy bool) []change {
+	var changes []change
+	var hostnames []string
+	ownedTypes := map[string][]string{}
+	for _, record := range ownedRecordsList(records, registry, owner) {
+		if _, ok := ownedTypes[record.Name]; !ok {
+			hostnames = append(hostnames, record.Name)
+		}
+		// Cloudflare lists a record for every value of the same type
+		if !slices.Contains(ownedTypes[record.Name], record.Type) {
+			ownedTypes[record.Name] = append(ownedTypes[record.Name], record.Type)
+		}
+	}
+
+	for _, hostname := range hostnames {
+		legacy, ok := lookupTXTRecord(registry.legacyTXTName(hostname), records)
+		if !ok || !ownedTXTRecord(legacy, registry, owner) {
+			continue
+		}
+		for _, recordType := range ownedTypes[hostname] {
+			typedName := registry.typedTXTName(hostname, recordType)
+			if _, ok := lookupTXTRecord(typedName, records); ok {
+				continue
+			}
+			typed := Record{
+				Name:   typedName,
+				Type:   "TXT",
+				TTL:    legacy.TTL,
+				Values: legacy.Values,
+			}
+			changes = append(changes, createChange(hostname, typed))
+		}
+		// The legacy TXT record is shared by all the record types of the
+		// hostname, and is only deleted once all of them have a typed one
+		if removeLegacy {
+			changes = append(changes, deleteChange(hostname, legacy))
+		}
+	}
+	return changes
+}
+
+// lookupTXTRecord returns the TXT record with the passed name
+func lookupTXTRecord(name string, records []Record) (Record, bool) {
+	for _, record := range records {
+		if record.Type == "TXT" && record.Name == sanitizeDNSAddress(name) {
+			return record, true
+		}
+	}
+	return Record{}, false
+}
+
+// ownedTXTRecord returns true if any of the TXT record values is owned by the
+// owner ID
+func ownedTXTRecord(record Record, registry txtRegistry, owner string) bool {
+	for _, value := range record.Values {
+		if registry.verifyOwner(value, owner) {
+			return true
+		}
+	}
+	return false
+}
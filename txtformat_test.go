@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanTXTFormatMigration(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	records := []Record{
+		// Only a legacy TXT record, shared by two record types
+		testRecord("a.example.com", "A", "1.1.1.1"),
+		testRecord("a.example.com", "AAAA", "::1"),
+		testRecord("infra-a.example.com", "TXT", registryValue("infra", "ingress/ns/a")),
+		// Already migrated
+		testRecord("b.example.com", "A", "2.2.2.2"),
+		testRecord("infra-b.example.com", "TXT", registryValue("infra", "ingress/ns/b")),
+		testRecord("infra-a-b.example.com", "TXT", registryValue("infra", "ingress/ns/b")),
+		// Owned by another owner ID
+		testRecord("c.example.com", "CNAME", "lb.example.net."),
+		testRecord("infra-c.example.com", "TXT", registryValue("other", "ingress/ns/c")),
+	}
+	tests := []struct {
+		name         string
+		removeLegacy bool
+		want         []string
+	}{
+		{
+			name: "creates the typed TXT records",
+			want: []string{
+				"create infra-a-a.example.com. TXT " + registryValue("infra", "ingress/ns/a"),
+				"create infra-aaaa-a.example.com. TXT " + registryValue("infra", "ingress/ns/a"),
+			},
+		},
+		{
+			name:         "deletes the legacy TXT records",
+			removeLegacy: true,
+			want: []string{
+				"create infra-a-a.example.com. TXT " + registryValue("infra", "ingress/ns/a"),
+				"create infra-aaaa-a.example.com. TXT " + registryValue("infra", "ingress/ns/a"),
+				"delete infra-a.example.com. TXT " + registryValue("infra", "ingress/ns/a"),
+				"delete infra-b.example.com. TXT " + registryValue("infra", "ingress/ns/b"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changeList(planTXTFormatMigration(records, registry, "infra", tt.removeLegacy))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planTXTFormatMigration() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanTXTFormatMigrationSeveralValues(t *testing.T) {
+	registry := newTestRegistry(t, "infra", "")
+	// Cloudflare lists a record for every value
+	records := []Record{
+		withID(testRecord("a.example.com", "A", "1.1.1.1"), "1"),
+		withID(testRecord("a.example.com", "A", "1.1.1.2"), "2"),
+		withID(testRecord("infra-a.example.com", "TXT", "heritage=external-dns,external-dns/owner=infra"), "3"),
+	}
+	want := []string{
+		"create infra-a-a.example.com. TXT heritage=external-dns,external-dns/owner=infra",
+		"delete infra-a.example.com. #3 TXT heritage=external-dns,external-dns/owner=infra",
+	}
+	if got := changeList(planTXTFormatMigration(records, registry, "infra", true)); !reflect.DeepEqual(got, want) {
+		t.Errorf("planTXTFormatMigration() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// dnsZone is a zone of a run against multiple zones. Hostnames are routed to
+// the zone with the longest domain they end with.
+type dnsZone struct {
+	domain   string
+	provider DNSProvider
+}
+
+// parseZones returns the zones of a comma separated list in the form of
+// <domain>=<provider>:<zone>. The domain can be left out for Cloudflare zones,
+// as they are named after it.
+func parseZones(specs string) ([]dnsZone, error) {
+	var zones []dnsZone
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		domain, providerSpec, ok := strings.Cut(spec, "=")
+		if !ok {
+			providerSpec = spec
+			domain = ""
+			if name, zone, _ := strings.Cut(spec, ":"); name == "cloudflare" {
+				domain = zone
+			}
+		}
+		if domain == "" {
+			return nil, fmt.Errorf("zones must be in the form of <domain>=<provider>:<zone>, got: %s", spec)
+		}
+		provider, err := parseZoneSpec(providerSpec)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, dnsZone{domain: sanitizeDNSAddress(domain), provider: provider})
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no zones found in: %s", specs)
+	}
+	return zones, nil
+}
+
+// matches returns the length of the zone domain if the hostname belongs to
+// it, or -1. A zone without a domain matches all the hostnames.
+func (z dnsZone) matches(hostname string) int {
+	hostname = sanitizeDNSAddress(hostname)
+	if z.domain == "" {
+		return 0
+	}
+	if hostname == z.domain || strings.HasSuffix(hostname, "."+z.domain) {
+		return len(z.domain)
+	}
+	return -1
+}
+
+// routeHostnames returns the hostnames of every zone, routing each hostname to
+// the zone with the longest matching domain
+func routeHostnames(hostnames []kubeHostname, zones []dnsZone) [][]kubeHostname {
+	routed := make([][]kubeHostname, len(zones))
+	for _, h := range hostnames {
+		best, bestLength := -1, -1
+		for i, z := range zones {
+			if l := z.matches(h.Hostname); l > bestLength {
+				best, bestLength = i, l
+			}
+		}
+		if best < 0 {
+			fmt.Printf("Skipping hostname: %s not found in any zone\n", h.Hostname)
+			continue
+		}
+		routed[best] = append(routed[best], h)
+	}
+	return routed
+}
+
+// runZones runs the function against every zone with the hostnames routed to
+// it. When running against multiple zones a failing zone does not stop the
+// run, and a summary of all the zones is printed at the end.
+func runZones(zones []dnsZone, hostnames []kubeHostname, opts runOptions, run func(provider DNSProvider, hostnames []kubeHostname, opts runOptions) error) error {
+	if len(zones) == 1 {
+		return run(zones[0].provider, routeHostnames(hostnames, zones)[0], opts)
+	}
+	summary := &runSummary{}
+	opts.summary = summary
+	var errs []error
+	for i, zoneHostnames := range routeHostnames(hostnames, zones) {
+		provider := zones[i].provider
+		fmt.Printf("Zone: %s, %s provider: %s\n", zones[i].domain, provider.Name(), provider.Zone())
+		if err := run(provider, zoneHostnames, opts); err != nil {
+			var failedErr *failedChangesError
+			if !errors.As(err, &failedErr) {
+				summary.add(zoneSummary{provider: provider.Name(), zone: provider.Zone(), err: err})
+			}
+			errs = append(errs, fmt.Errorf("%s zone: %s, %v", provider.Name(), provider.Zone(), err))
+		}
+	}
+	summary.print()
+	return errors.Join(errs...)
+}
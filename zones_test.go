@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// zoneFile writes an empty memory zone file and returns its path
+func zoneFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+		t.Fatalf("failed to write zone file: %v", err)
+	}
+	return path
+}
+
+func TestParseZones(t *testing.T) {
+	dir := t.TempDir()
+	a := zoneFile(t, dir, "a.json")
+	b := zoneFile(t, dir, "b.json")
+	zones, err := parseZones("example.com=memory:" + a + ", dev.example.com.=memory:" + b)
+	if err != nil {
+		t.Fatalf("parseZones() error = %v", err)
+	}
+	var got []string
+	for _, z := range zones {
+		got = append(got, z.domain+" "+z.provider.Zone())
+	}
+	if want := []string{"example.com. " + a, "dev.example.com. " + b}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseZones() = %q, want %q", got, want)
+	}
+}
+
+func TestParseZonesErrors(t *testing.T) {
+	a := zoneFile(t, t.TempDir(), "a.json")
+	tests := []struct {
+		name  string
+		specs string
+	}{
+		{name: "no zones", specs: " , "},
+		{name: "no domain", specs: "memory:" + a},
+		{name: "no zone", specs: "example.com=memory:"},
+		{name: "unknown provider", specs: "example.com=bind:example.com"},
+		{name: "gcp zone without a project", specs: "example.com=gcp:dev-zone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseZones(tt.specs); err == nil {
+				t.Error("parseZones() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestRouteHostnames(t *testing.T) {
+	zones := []dnsZone{
+		{domain: "example.com."},
+		{domain: "dev.example.com."},
+		{domain: "example.org."},
+	}
+	hostnames := []kubeHostname{
+		{Hostname: "a.example.com"},
+		{Hostname: "a.dev.example.com"},
+		{Hostname: "dev.example.com."},
+		{Hostname: "a.notdev.example.com"},
+		{Hostname: "example.org"},
+		{Hostname: "a.example.net"},
+	}
+	want := [][]string{
+		{"a.example.com", "a.notdev.example.com"},
+		{"a.dev.example.com", "dev.example.com."},
+		{"example.org"},
+	}
+	routed := routeHostnames(hostnames, zones)
+	var got [][]string
+	for _, zoneHostnames := range routed {
+		got = append(got, hostnamesList(zoneHostnames))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("routeHostnames() = %q, want %q", got, want)
+	}
+}
+
+func TestRouteHostnamesWithoutDomain(t *testing.T) {
+	// A single zone without a domain gets all the hostnames
+	hostnames := []kubeHostname{{Hostname: "a.example.com"}, {Hostname: "a.example.net"}}
+	routed := routeHostnames(hostnames, []dnsZone{{}})
+	if got := hostnamesList(routed[0]); !reflect.DeepEqual(got, []string{"a.example.com", "a.example.net"}) {
+		t.Errorf("routeHostnames() = %q, want all the hostnames", got)
+	}
+}
+
+func TestRunZones(t *testing.T) {
+	zones := []dnsZone{
+		{domain: "example.com.", provider: newMemoryProvider("a")},
+		{domain: "example.org.", provider: newMemoryProvider("b")},
+	}
+	hostnames := []kubeHostname{{Hostname: "a.example.com"}, {Hostname: "a.example.org"}}
+	var ran []string
+	err := runZones(zones, hostnames, runOptions{}, func(provider DNSProvider, hostnames []kubeHostname, opts runOptions) error {
+		ran = append(ran, provider.Zone()+" "+hostnamesList(hostnames)[0])
+		if opts.summary == nil {
+			t.Errorf("zone: %s run without a summary", provider.Zone())
+		}
+		if provider.Zone() == "a" {
+			return errors.New("request failed")
+		}
+		return nil
+	})
+	// A failing zone does not stop the run
+	if want := []string{"a a.example.com", "b a.example.org"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("runZones() ran = %q, want %q", ran, want)
+	}
+	if err == nil {
+		t.Error("runZones() error = nil, want the error of zone a")
+	}
+}